@@ -0,0 +1,173 @@
+package bp35a1
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* Coordinator */
+type Coordinator interface {
+	IpAddr() net.IP
+	HwAddr() string
+	Channel() uint8
+	PanId() uint16
+	LQI() uint8
+	PairId() string
+	LastSeen() time.Time
+	Beacons() int
+}
+
+type coordinator struct {
+	ipaddr   net.IP
+	hwaddr   string
+	channel  uint8
+	panid    uint16
+	lqi      uint8
+	pairid   string
+	lastseen time.Time
+	beacons  int
+}
+
+func (c *coordinator) IpAddr() net.IP {
+	return c.ipaddr
+}
+
+func (c *coordinator) HwAddr() string {
+	return c.hwaddr
+}
+
+func (c *coordinator) Channel() uint8 {
+	return c.channel
+}
+
+func (c *coordinator) PanId() uint16 {
+	return c.panid
+}
+
+func (c *coordinator) LQI() uint8 {
+	return c.lqi
+}
+
+func (c *coordinator) PairId() string {
+	return c.pairid
+}
+
+func (c *coordinator) LastSeen() time.Time {
+	return c.lastseen
+}
+
+func (c *coordinator) Beacons() int {
+	return c.beacons
+}
+
+/* BeaconView */
+type BeaconView interface {
+	Coordinators() []Coordinator
+	Coordinator(panid uint16) Coordinator
+	Silent(panid uint16, maxAge time.Duration) bool
+}
+
+type beaconView struct {
+	mutex  *sync.Mutex
+	coords map[string]*coordinator
+}
+
+func NewBeaconView(c Controller) BeaconView {
+	v := &beaconView{
+		mutex:  new(sync.Mutex),
+		coords: make(map[string]*coordinator)}
+
+	c.RegisterHandler(EVENT, func(e Event) {
+		if b, ok := e.(EventBeacon); ok {
+			v.beacon(b)
+		}
+	})
+	c.RegisterHandler(EPANDESC, func(e Event) {
+		v.pandesc(e.(EventPanDesc))
+	})
+
+	return v
+}
+
+func (v *beaconView) Coordinators() []Coordinator {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	cs := make([]Coordinator, 0, len(v.coords))
+	for _, c := range v.coords {
+		cp := *c
+		cs = append(cs, &cp)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].LastSeen().After(cs[j].LastSeen())
+	})
+	return cs
+}
+
+func (v *beaconView) Coordinator(panid uint16) Coordinator {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	var found *coordinator
+	for _, c := range v.coords {
+		if c.panid == panid && (found == nil || c.lastseen.After(found.lastseen)) {
+			found = c
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	cp := *found
+	return &cp
+}
+
+func (v *beaconView) Silent(panid uint16, maxAge time.Duration) bool {
+	c := v.Coordinator(panid)
+	return c == nil || time.Since(c.LastSeen()) > maxAge
+}
+
+func (v *beaconView) beacon(e EventBeacon) {
+	hw := e.HwAddr()
+	if hw == "" {
+		return
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	c := v.get(hw)
+	c.ipaddr = e.Sender()
+	c.lastseen = e.Received()
+	c.beacons++
+}
+
+func (v *beaconView) pandesc(e EventPanDesc) {
+	hw := strings.ToUpper(e.Addr())
+	if hw == "" {
+		return
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	c := v.get(hw)
+	c.channel = e.Channel()
+	c.panid = e.PanId()
+	c.lqi = e.LQI()
+	c.pairid = e.PairId()
+	if c.lastseen.IsZero() {
+		c.lastseen = time.Now()
+	}
+}
+
+func (v *beaconView) get(hw string) *coordinator {
+	c, ok := v.coords[hw]
+	if !ok {
+		c = &coordinator{hwaddr: hw}
+		v.coords[hw] = c
+	}
+	return c
+}
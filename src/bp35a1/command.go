@@ -3,8 +3,10 @@ package bp35a1
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 )
 
@@ -49,6 +51,7 @@ const (
 type Command interface {
 	String() string
 	Parameters() []interface{}
+	ExpectsResult() bool
 }
 
 type command struct {
@@ -63,6 +66,16 @@ func (c *command) Parameters() []interface{} {
 	return []interface{}{}
 }
 
+// Whether a bare result line (neither OK, FAIL nor an event) answers the
+// command.
+func (c *command) ExpectsResult() bool {
+	switch c.id {
+	case SKINFO, SKTABLE, SKVER, SKAPPVER, SKLL64:
+		return true
+	}
+	return false
+}
+
 type command_sreg struct {
 	*command
 	reg uint8
@@ -70,11 +83,19 @@ type command_sreg struct {
 }
 
 func (c *command_sreg) Parameters() []interface{} {
+	if c.val == "" {
+		return []interface{}{fmt.Sprintf("S%02X", c.reg)}
+	}
 	return []interface{}{
 		fmt.Sprintf("S%02X", c.reg),
 		c.val}
 }
 
+// Only reads answer with a value.
+func (c *command_sreg) ExpectsResult() bool {
+	return c.val == ""
+}
+
 type command_join struct {
 	*command
 	ipaddr net.IP
@@ -211,7 +232,7 @@ type command_secenable struct {
 
 func (c *command_secenable) Parameters() []interface{} {
 	return []interface{}{
-		fmt.Sprintf("%04X", c.mode),
+		fmt.Sprintf("%X", c.mode),
 		iptoa(c.ipaddr),
 		c.hwaddr}
 }
@@ -310,6 +331,8 @@ func (c *command_ll64) Parameters() []interface{} {
 	return []interface{}{strings.ToUpper(c.hwaddr)}
 }
 
+// Deprecated: NewCommand panics on parameters of the wrong type. Use the
+// typed constructors such as Scan, SendTo and SetPassword instead.
 func NewCommand(id cmd, params ...interface{}) Command {
 	c := &command{id: id}
 	switch id {
@@ -416,11 +439,287 @@ func NewCommand(id cmd, params ...interface{}) Command {
 	case SKLL64:
 		return &command_ll64{
 			command: c,
-			hwaddr:  params[1].(string)}
+			hwaddr:  params[len(params)-1].(string)}
 	}
 	return c
 }
 
+const (
+	MAX_UDP_DATA = 1232
+	MAX_TCP_DATA = 1232
+)
+
+func SetRegister(reg uint8, val string) (Command, error) {
+	if val == "" {
+		return nil, errors.New("Register value is empty.")
+	}
+	if _, err := strconv.ParseUint(val, 16, 64); err != nil {
+		return nil, fmt.Errorf("Invalid register value: %s", val)
+	}
+	return &command_sreg{command: &command{id: SKSREG}, reg: reg, val: val}, nil
+}
+
+func GetRegister(reg uint8) Command {
+	return &command_sreg{command: &command{id: SKSREG}, reg: reg}
+}
+
+func Info() Command {
+	return &command{id: SKINFO}
+}
+
+func Start() Command {
+	return &command{id: SKSTART}
+}
+
+func Join(ipaddr net.IP) (Command, error) {
+	if err := checkIP(ipaddr); err != nil {
+		return nil, err
+	}
+	return &command_join{command: &command{id: SKJOIN}, ipaddr: ipaddr}, nil
+}
+
+func Rejoin() Command {
+	return &command{id: SKREJOIN}
+}
+
+func Term() Command {
+	return &command{id: SKTERM}
+}
+
+func SendTo(handle uint8, ipaddr net.IP, port uint16, sec uint8, data []byte) (Command, error) {
+	if err := checkHandle(handle, 6); err != nil {
+		return nil, err
+	}
+	if err := checkIP(ipaddr); err != nil {
+		return nil, err
+	}
+	if port == 0 {
+		return nil, errors.New("Port must not be 0.")
+	}
+	if sec > 2 {
+		return nil, fmt.Errorf("Invalid security flag: %d", sec)
+	}
+	if len(data) == 0 || len(data) > MAX_UDP_DATA {
+		return nil, fmt.Errorf("Invalid data length: %d", len(data))
+	}
+	return &command_sendto{
+		command: &command{id: SKSENDTO},
+		handle:  handle,
+		ipaddr:  ipaddr,
+		port:    port,
+		sec:     sec,
+		data:    data}, nil
+}
+
+func Connect(ipaddr net.IP, rport uint16, lport uint16) (Command, error) {
+	if err := checkIP(ipaddr); err != nil {
+		return nil, err
+	}
+	if rport == 0 || lport == 0 {
+		return nil, errors.New("Port must not be 0.")
+	}
+	return &command_connect{
+		command: &command{id: SKCONNECT},
+		ipaddr:  ipaddr,
+		rport:   rport,
+		lport:   lport}, nil
+}
+
+func SendTCP(handle uint8, data []byte) (Command, error) {
+	if err := checkHandle(handle, 0xFF); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data) > MAX_TCP_DATA {
+		return nil, fmt.Errorf("Invalid data length: %d", len(data))
+	}
+	return &command_send{command: &command{id: SKSEND}, handle: handle, data: data}, nil
+}
+
+func Close(handle uint8) (Command, error) {
+	if err := checkHandle(handle, 0xFF); err != nil {
+		return nil, err
+	}
+	return &command_close{command: &command{id: SKCLOSE}, handle: handle}, nil
+}
+
+func Ping(ipaddr net.IP) (Command, error) {
+	if err := checkIP(ipaddr); err != nil {
+		return nil, err
+	}
+	return &command_ping{command: &command{id: SKPING}, ipaddr: ipaddr}, nil
+}
+
+func Scan(mode uint8, mask uint32, duration uint8) (Command, error) {
+	if mode != 0 && mode != 2 && mode != 3 {
+		return nil, fmt.Errorf("Invalid scan mode: %d", mode)
+	}
+	if mask == 0 {
+		return nil, errors.New("Channel mask must not be 0.")
+	}
+	if duration > 14 {
+		return nil, fmt.Errorf("Invalid scan duration: %d", duration)
+	}
+	return &command_scan{
+		command:  &command{id: SKSCAN},
+		mode:     mode,
+		mask:     mask,
+		duration: duration}, nil
+}
+
+func RegDev(ipaddr net.IP) (Command, error) {
+	if err := checkIP(ipaddr); err != nil {
+		return nil, err
+	}
+	return &command_regdev{command: &command{id: SKREGDEV}, ipaddr: ipaddr}, nil
+}
+
+func RmDev(ipaddr net.IP) (Command, error) {
+	if err := checkIP(ipaddr); err != nil {
+		return nil, err
+	}
+	return &command_rmdev{command: &command{id: SKRMDEV}, ipaddr: ipaddr}, nil
+}
+
+func SetKey(index uint8, key []byte) (Command, error) {
+	if len(key) != 16 {
+		return nil, errors.New("MAC key must be 16 bytes.")
+	}
+	return &command_setkey{command: &command{id: SKSETKEY}, index: index, key: key}, nil
+}
+
+func RmKey(index uint8) Command {
+	return &command_rmkey{command: &command{id: SKRMKEY}, index: index}
+}
+
+func SecEnable(enable bool, ipaddr net.IP, hwaddr string) (Command, error) {
+	if err := checkIP(ipaddr); err != nil {
+		return nil, err
+	}
+	if err := checkHwAddr(hwaddr); err != nil {
+		return nil, err
+	}
+	c := &command_secenable{command: &command{id: SKSECENABLE}, ipaddr: ipaddr, hwaddr: hwaddr}
+	if enable {
+		c.mode = 1
+	}
+	return c, nil
+}
+
+func SetPSK(key []byte) (Command, error) {
+	if len(key) != 16 {
+		return nil, errors.New("PSK must be 16 bytes.")
+	}
+	return &command_setpsk{command: &command{id: SKSETPSK}, key: key}, nil
+}
+
+func SetPassword(pwd string) (Command, error) {
+	if len(pwd) < 1 || len(pwd) > 32 {
+		return nil, errors.New("Password must be 1 to 32 characters.")
+	}
+	AddSecret(pwd)
+	return &command_setpwd{command: &command{id: SKSETPWD}, pwd: pwd}, nil
+}
+
+func SetRouteBId(rbid string) (Command, error) {
+	if len(rbid) != 32 {
+		return nil, errors.New("Route B ID must be 32 characters.")
+	}
+	AddSecret(rbid)
+	return &command_setrbid{command: &command{id: SKSETRBID}, rbid: rbid}, nil
+}
+
+func AddNbr(ipaddr net.IP, hwaddr string) (Command, error) {
+	if err := checkIP(ipaddr); err != nil {
+		return nil, err
+	}
+	if err := checkHwAddr(hwaddr); err != nil {
+		return nil, err
+	}
+	return &command_addnbr{command: &command{id: SKADDNBR}, ipaddr: ipaddr, hwaddr: hwaddr}, nil
+}
+
+func UdpPort(handle uint8, port uint16) (Command, error) {
+	if err := checkHandle(handle, 6); err != nil {
+		return nil, err
+	}
+	return &command_udpport{command: &command{id: SKUDPPORT}, handle: handle, port: port}, nil
+}
+
+func TcpPort(index uint8, port uint16) (Command, error) {
+	if err := checkHandle(index, 4); err != nil {
+		return nil, err
+	}
+	return &command_tcpport{command: &command{id: SKTCPPORT}, index: index, port: port}, nil
+}
+
+func Save() Command {
+	return &command{id: SKSAVE}
+}
+
+func Load() Command {
+	return &command{id: SKLOAD}
+}
+
+func Erase() Command {
+	return &command{id: SKERASE}
+}
+
+func Ver() Command {
+	return &command{id: SKVER}
+}
+
+func AppVer() Command {
+	return &command{id: SKAPPVER}
+}
+
+func Reset() Command {
+	return &command{id: SKRESET}
+}
+
+func Table(mode uint8) Command {
+	return &command_table{command: &command{id: SKTABLE}, mode: mode}
+}
+
+func DSleep() Command {
+	return &command{id: SKDSLEEP}
+}
+
+func Rflo(mode uint8) (Command, error) {
+	if mode > 1 {
+		return nil, fmt.Errorf("Invalid RF mode: %d", mode)
+	}
+	return &command_rflo{command: &command{id: SKRFLO}, mode: mode}, nil
+}
+
+func LL64(hwaddr string) (Command, error) {
+	if err := checkHwAddr(hwaddr); err != nil {
+		return nil, err
+	}
+	return &command_ll64{command: &command{id: SKLL64}, hwaddr: hwaddr}, nil
+}
+
+func checkIP(ip net.IP) error {
+	if ip == nil || ip.To4() != nil || ip.To16() == nil {
+		return fmt.Errorf("Invalid IPv6 address: %v", ip)
+	}
+	return nil
+}
+
+func checkHwAddr(hwaddr string) error {
+	b, err := hex.DecodeString(hwaddr)
+	if err != nil || len(b) != 8 {
+		return fmt.Errorf("Invalid MAC address: %s", hwaddr)
+	}
+	return nil
+}
+
+func checkHandle(handle uint8, max uint8) error {
+	if handle < 1 || handle > max {
+		return fmt.Errorf("Invalid handle: %d", handle)
+	}
+	return nil
+}
+
 func iptoa(ip net.IP) string {
 	var v bytes.Buffer
 	bt := []byte(ip)
@@ -0,0 +1,33 @@
+package bp35a1
+
+import (
+	"net"
+	"testing"
+)
+
+func TestExpectsResult(t *testing.T) {
+	must := func(c Command, err error) Command {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		cmd  Command
+		want bool
+	}{
+		{must(LL64("001D129012345678")), true},
+		{Info(), true},
+		{Ver(), true},
+		{GetRegister(0x02), true},
+		{must(SetRegister(0x02, "21")), false},
+		{must(Join(net.ParseIP("fe80::21d:1290:1234:5678"))), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cmd.ExpectsResult(); got != tt.want {
+			t.Errorf("%s %v: ExpectsResult() = %v, want %v", tt.cmd, tt.cmd.Parameters(), got, tt.want)
+		}
+	}
+}
@@ -16,57 +16,129 @@ type condition func(Event) bool
 
 type Controller interface {
 	Send(Command, ...condition) Response
+	SendPriority(pr, Command, ...condition) Response
+	SetMaxQueueAge(pr, time.Duration)
+	Begin(Command) Operation
+	Expect(condition, time.Duration) Operation
 	RegisterHandler(ev, ...handler)
+	RegisterService(uint16, ...udpHandler) error
+	UnregisterService(uint16) error
+	Sleep() error
+	Wake() error
+	Sleeping() bool
+	Stats() Stats
 }
 
 type controller struct {
 	handlers map[ev][]handler
+	hmutex   *sync.Mutex
+	services map[uint16]*service
+	smutex   *sync.Mutex
+	regmutex *sync.Mutex
 	watchers map[chan<- Event]func()
 	mutex    *sync.Mutex
-	send     chan Command
+	queue    *queue
+	busy     int
 	recv     chan Event
-	resp     chan Response
+	pmutex   *sync.Mutex
+	current  *pending
+	echo     bool
+	port     io.Writer
+	wmutex   *sync.Mutex
+	slmutex  *sync.Mutex
+	sleeping bool
+	wakeup   chan struct{}
+	stats    *stats
+}
+
+type SerialConfig struct {
+	Name        string
+	Baud        int
+	ReadTimeout time.Duration
 }
 
 func NewController(tty string) Controller {
-	ser, err := serial.OpenPort(&serial.Config{Name: tty, Baud: 115200})
+	return NewSerialController(&SerialConfig{Name: tty})
+}
+
+func NewSerialController(conf *SerialConfig) Controller {
+	baud := conf.Baud
+	if baud <= 0 {
+		baud = 115200
+	}
+
+	ser, err := serial.OpenPort(&serial.Config{Name: conf.Name, Baud: baud, ReadTimeout: conf.ReadTimeout})
 	if err != nil {
 		log.Critical(err)
 	}
 
 	c := &controller{
 		handlers: make(map[ev][]handler),
+		hmutex:   new(sync.Mutex),
+		services: make(map[uint16]*service),
+		smutex:   new(sync.Mutex),
+		regmutex: new(sync.Mutex),
 		watchers: make(map[chan<- Event]func()),
 		mutex:    new(sync.Mutex),
-		send:     make(chan Command),
+		queue:    newQueue(),
 		recv:     make(chan Event),
-		resp:     make(chan Response)}
+		pmutex:   new(sync.Mutex),
+		echo:     true,
+		port:     ser,
+		wmutex:   new(sync.Mutex),
+		slmutex:  new(sync.Mutex),
+		stats:    newStats()}
 
-	resp := make(chan interface{})
-	go c.reciever(ser, resp)
-	go c.sender(ser, resp)
+	go c.reciever(&blockingReader{rd: ser, stall: c.stats.stall})
+	go c.sender(ser)
 	go c.processEvent()
 
 	return c
 }
 
 func (c *controller) Send(cmd Command, cond ...condition) Response {
+	return c.SendPriority(INTERACTIVE, cmd, cond...)
+}
+
+func (c *controller) SendPriority(p pr, cmd Command, cond ...condition) Response {
+	if c.Sleeping() {
+		if err := c.Wake(); err != nil {
+			log.Error(err)
+		}
+	}
+
+	pd := newPending(cmd)
+	pd.pr = p
+
+	// The condition timeout starts when the command is sent, not while it
+	// waits in the queue; a dropped command ends the wait.
 	var wg sync.WaitGroup
 	for _, cn := range cond {
 		wg.Add(1)
-		w := make(chan Event)
+		w := make(chan Event, 16)
 		f := func() {
 			defer wg.Done()
-			defer close(w)
-			defer delete(c.watchers, w)
+			defer c.removeWatcher(w)
 
+			sent, done := pd.sent, pd.done
+			var timeout <-chan time.Time
 			for {
 				select {
 				case e := <-w:
 					if cn(e) {
 						return
 					}
-				case <-time.After(time.Second * 10):
+				case <-sent:
+					sent = nil
+					timeout = time.After(time.Second * 10)
+				case <-done:
+					select {
+					case <-pd.sent:
+					default:
+						return // dropped
+					}
+					done = nil
+				case <-timeout:
 					return
 				}
 			}
@@ -75,14 +147,27 @@ func (c *controller) Send(cmd Command, cond ...condition) Response {
 		go f()
 	}
 
-	c.send <- cmd
-	r := <-c.resp
+	c.queue.push(pd)
+	r := <-pd.resp
 
 	wg.Wait()
 	return r
 }
 
+func (c *controller) SetMaxQueueAge(p pr, age time.Duration) {
+	c.queue.setMaxAge(p, age)
+}
+
+func (c *controller) Stats() Stats {
+	st := c.stats.snapshot()
+	st.depth = c.queue.depth()
+	return st
+}
+
 func (c *controller) RegisterHandler(e ev, hdr ...handler) {
+	c.hmutex.Lock()
+	defer c.hmutex.Unlock()
+
 	if _, ok := c.handlers[e]; !ok {
 		c.handlers[e] = []handler{}
 	}
@@ -90,78 +175,142 @@ func (c *controller) RegisterHandler(e ev, hdr ...handler) {
 	c.handlers[e] = append(c.handlers[e], hdr...)
 }
 
+func (c *controller) setBusy(d int) {
+	c.pmutex.Lock()
+	defer c.pmutex.Unlock()
+	c.busy += d
+}
+
+func (c *controller) isBusy() bool {
+	c.pmutex.Lock()
+	defer c.pmutex.Unlock()
+	return c.busy > 0
+}
+
 func (c *controller) addWatcher(w chan Event, f func()) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.watchers[w] = f
 }
 
-func (c *controller) sender(wt io.Writer, resp <-chan interface{}) {
-	result := make(chan bool)
-	for c := range c.send {
-		go func() {
-			select {
-			case <-resp:
-				result <- true
-			case <-time.After(time.Second * 2):
-				result <- false
-			}
-		}()
-		_, err := wt.Write(append(ToBytes(c), []byte("\r\n")...))
+func (c *controller) removeWatcher(w chan Event) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.watchers, w)
+	close(w)
+}
 
-		<-result
+func (c *controller) sender(wt io.Writer) {
+	for {
+		p, dropped := c.queue.pop(c.isBusy)
+		for _, d := range dropped {
+			log.Debugf("Drop stale %s", d.cmd)
+			c.stats.drop()
+			d.drop()
+		}
+		if p == nil {
+			continue
+		}
+
+		c.begin(p)
+		n, err := c.write(wt, append(ToBytes(p.cmd), []byte("\r\n")...))
+		c.stats.command(p.cmd, n)
 		if err != nil {
 			log.Critical(err)
+			c.complete(p, nil)
+			continue
+		}
+
+		select {
+		case <-p.done:
+		case <-time.After(time.Second * 2):
+			if c.complete(p, nil) {
+				c.stats.timeout()
+			}
+		}
+	}
+}
+
+// With a read timeout the port returns empty reads, (0, io.EOF) from
+// os.File, which bufio.Scanner would give up on. Each of them is counted as
+// a stall.
+type blockingReader struct {
+	rd    io.Reader
+	stall func()
+}
+
+func (r *blockingReader) Read(b []byte) (int, error) {
+	for {
+		n, err := r.rd.Read(b)
+		if n > 0 || (err != nil && err != io.EOF) {
+			return n, err
 		}
+		r.stall()
 	}
 }
 
-func (c *controller) reciever(rd io.Reader, resp chan<- interface{}) {
+func (c *controller) reciever(rd io.Reader) {
 	var m MultiLine
 	var ln []string
 
 	f := func() {
 		if m != nil {
-			m.Parse(ln)
-			c.recv <- m.(Event)
+			if err := parseLines(m, ln); err != nil {
+				log.Warn(err)
+				c.stats.line(0, false)
+			} else {
+				c.recv <- m.(Event)
+			}
 			m = nil
 		}
 	}
 
 	s := bufio.NewScanner(rd)
 	for s.Scan() {
-		log.Debug(s.Text())
+		log.Debug(Redact(s.Text()))
 		data := s.Text()
+		c.stats.line(len(s.Bytes())+2, true)
 		switch {
-		case strings.HasPrefix(data, "SK"): // Ignore echo back
+		case strings.HasPrefix(data, "SK"): // Echo back
 			f()
+
+			c.echoed(data)
 		case strings.HasPrefix(data, "E"):
 			f()
 
-			e := newEvent(data)
+			e, err := parseEvent(data)
+			if err != nil {
+				log.Warn(err)
+				c.stats.line(0, false)
+				continue
+			}
+			if ee, ok := e.(EventEvent); ok && ee.Num() == 0xC0 { // Wake up
+				c.awake()
+			}
 			m, _ = e.(MultiLine)
 			if m != nil {
 				ln = []string{}
 			} else {
 				c.recv <- e
 			}
+		case c.Sleeping():
+			f()
+
+			log.Debug("Ignore output while sleeping")
 		case strings.HasPrefix(data, "OK"):
 			f()
 
-			c.resp <- &response{t: OK}
-			resp <- true
+			c.respond(&response{t: OK}, data)
 		case strings.HasPrefix(data, "FAIL"):
 			f()
 
 			r := strings.Split(data, " ")
-			c.resp <- &response_fail{response: &response{t: FAIL}, code: string(r[1])}
-			resp <- false
+			c.respond(&response_fail{response: &response{t: FAIL}, code: string(r[1])}, data)
 		default:
 			if m != nil {
 				ln = append(ln, data)
 			} else if len(data) > 0 {
-				c.resp <- &response_result{response: &response{t: RESULT}, result: string(data)}
-				resp <- true
+				c.respond(&response_result{response: &response{t: RESULT}, result: string(data)}, data)
 			}
 		}
 	}
@@ -170,14 +319,24 @@ func (c *controller) reciever(rd io.Reader, resp chan<- interface{}) {
 func (c *controller) processEvent() {
 	for {
 		e := <-c.recv
-		if hdr, ok := c.handlers[e.Type()]; ok {
-			for _, h := range hdr {
-				h(e)
-			}
+		c.stats.event(e.Type())
+		c.hmutex.Lock()
+		hdr := c.handlers[e.Type()]
+		c.hmutex.Unlock()
+		for _, h := range hdr {
+			h(e)
+		}
+		if e.Type() == ERXUDP {
+			c.dispatch(e.(EventRxUDP))
 		}
 
+		c.mutex.Lock()
 		for w, _ := range c.watchers {
-			w <- e
+			select {
+			case w <- e:
+			default:
+			}
 		}
+		c.mutex.Unlock()
 	}
 }
@@ -0,0 +1,51 @@
+package bp35a1
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"testing"
+)
+
+// Returns each read in turn, then the final error.
+type scriptedReader struct {
+	reads []string
+	err   error
+}
+
+func (r *scriptedReader) Read(b []byte) (int, error) {
+	if len(r.reads) == 0 {
+		return 0, r.err
+	}
+	s := r.reads[0]
+	r.reads = r.reads[1:]
+	if s == "" {
+		return 0, io.EOF
+	}
+	return copy(b, s), nil
+}
+
+func TestBlockingReader(t *testing.T) {
+	errClosed := errors.New("closed")
+	rd := &scriptedReader{
+		reads: []string{"", "OK\r\n", "", "", "EVENT 21 FE80:0000:0000:0000:0000:0000:0000:0001 00\r\n"},
+		err:   errClosed}
+
+	var stalls int
+	s := bufio.NewScanner(&blockingReader{rd: rd, stall: func() { stalls++ }})
+
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+
+	if len(lines) != 2 || lines[0] != "OK" {
+		t.Errorf("lines = %q", lines)
+	}
+	if stalls != 3 {
+		t.Errorf("stalls = %d, want 3", stalls)
+	}
+	if s.Err() != errClosed {
+		t.Errorf("err = %v, want %v", s.Err(), errClosed)
+	}
+}
@@ -2,9 +2,11 @@ package bp35a1
 
 import (
 	"encoding/hex"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type ev int
@@ -572,6 +574,13 @@ type EventEvent interface {
 	Param() []byte
 }
 
+const (
+	EVENT_NS_RECEIVED     uint8 = 0x01
+	EVENT_NA_RECEIVED     uint8 = 0x02
+	EVENT_ECHO_REQUEST    uint8 = 0x05
+	EVENT_BEACON_RECEIVED uint8 = 0x20
+)
+
 type event_event struct {
 	*event
 	num    uint8
@@ -591,11 +600,100 @@ func (e *event_event) Param() []byte {
 	return e.param
 }
 
+/* EventNeighborDiscovery */
+type EventNeighborDiscovery interface {
+	EventEvent
+	HwAddr() string
+	Advertisement() bool
+}
+
+type event_nd struct {
+	*event_event
+}
+
+func (e *event_nd) HwAddr() string {
+	return lltohw(e.sender)
+}
+
+func (e *event_nd) Advertisement() bool {
+	return e.num == EVENT_NA_RECEIVED
+}
+
+/* EventEchoRequest */
+type EventEchoRequest interface {
+	EventEvent
+	Requester() net.IP
+}
+
+type event_echoreq struct {
+	*event_event
+}
+
+func (e *event_echoreq) Requester() net.IP {
+	return e.sender
+}
+
+/* EventBeacon */
+type EventBeacon interface {
+	EventEvent
+	HwAddr() string
+	Received() time.Time
+}
+
+type event_beacon struct {
+	*event_event
+	received time.Time
+}
+
+func (e *event_beacon) HwAddr() string {
+	return lltohw(e.sender)
+}
+
+func (e *event_beacon) Received() time.Time {
+	return e.received
+}
+
+// The MAC address embedded in a link-local address (the reverse of SKLL64).
+func lltohw(ip net.IP) string {
+	ip = ip.To16()
+	if ip == nil || !ip.IsLinkLocalUnicast() {
+		return ""
+	}
+	b := append([]byte{}, ip[8:]...)
+	b[0] ^= 0x02
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
 func atoi(s string) int {
 	i64, _ := strconv.ParseInt(s, 16, 0)
 	return int(i64)
 }
 
+func parseEvent(data string) (e Event, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, err = nil, fmt.Errorf("Malformed event: %s", data)
+		}
+	}()
+
+	e = newEvent(data)
+	if e.Type() < 0 {
+		return nil, fmt.Errorf("Unknown event: %s", data)
+	}
+	return e, nil
+}
+
+func parseLines(m MultiLine, data []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Malformed %v: %v", m.(Event).Type(), data)
+		}
+	}()
+
+	m.Parse(data)
+	return nil
+}
+
 func newEvent(data string) Event {
 	d := strings.Split(data, " ")
 
@@ -700,11 +798,24 @@ func newEvent(data string) Event {
 					h, _ := hex.DecodeString(d[3])
 					return h
 				}()}
-		} else {
-			return &event_event{
+		} else if len(d) > 2 {
+			ee := &event_event{
 				event:  e,
 				num:    n,
 				sender: net.ParseIP(d[2])}
+			switch n {
+			case EVENT_NS_RECEIVED, EVENT_NA_RECEIVED:
+				return &event_nd{event_event: ee}
+			case EVENT_ECHO_REQUEST:
+				return &event_echoreq{event_event: ee}
+			case EVENT_BEACON_RECEIVED:
+				return &event_beacon{event_event: ee, received: time.Now()}
+			}
+			return ee
+		} else {
+			return &event_event{
+				event: e,
+				num:   n}
 		}
 	}
 
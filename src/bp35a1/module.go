@@ -0,0 +1,135 @@
+package bp35a1
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	REG_CHANNEL     uint8 = 0x02
+	REG_PAN_ID      uint8 = 0x03
+	REG_AUTO_REAUTH uint8 = 0x17
+	REG_ECHO_BACK   uint8 = 0xFE
+	REG_AUTO_LOAD   uint8 = 0xFF
+)
+
+type Registers struct {
+	Channel    uint8
+	PanId      uint16
+	AutoReauth bool
+	AutoLoad   bool
+}
+
+type Module interface {
+	Register(uint8) (string, error)
+	SetRegister(uint8, string) error
+	Registers() (*Registers, error)
+	SetRegisters(*Registers) error
+	Save() error
+	Load() error
+	Erase() error
+}
+
+type module struct {
+	ctrl Controller
+}
+
+func NewModule(c Controller) Module {
+	return &module{ctrl: c}
+}
+
+func (m *module) Register(reg uint8) (string, error) {
+	var val string
+	r := m.ctrl.Send(GetRegister(reg),
+		func(e Event) bool {
+			if e.Type() == ESREG {
+				val = e.(EventSreg).Val()
+				return true
+			}
+			return false
+		})
+	if err := toError(r); err != nil {
+		return "", err
+	}
+	if val == "" {
+		return "", fmt.Errorf("No value for register S%02X.", reg)
+	}
+	return val, nil
+}
+
+func (m *module) SetRegister(reg uint8, val string) error {
+	c, err := SetRegister(reg, val)
+	if err != nil {
+		return err
+	}
+	return toError(m.ctrl.Send(c))
+}
+
+func (m *module) Registers() (*Registers, error) {
+	var regs Registers
+	for _, reg := range []uint8{REG_CHANNEL, REG_PAN_ID, REG_AUTO_REAUTH, REG_AUTO_LOAD} {
+		val, err := m.Register(reg)
+		if err != nil {
+			return nil, err
+		}
+
+		switch reg {
+		case REG_CHANNEL:
+			regs.Channel = uint8(atoi(val))
+		case REG_PAN_ID:
+			regs.PanId = uint16(atoi(val))
+		case REG_AUTO_REAUTH:
+			regs.AutoReauth = atoi(val) != 0
+		case REG_AUTO_LOAD:
+			regs.AutoLoad = atoi(val) != 0
+		}
+	}
+	return &regs, nil
+}
+
+func (m *module) SetRegisters(regs *Registers) error {
+	vals := map[uint8]string{
+		REG_CHANNEL:     fmt.Sprintf("%02X", regs.Channel),
+		REG_PAN_ID:      fmt.Sprintf("%04X", regs.PanId),
+		REG_AUTO_REAUTH: btoa(regs.AutoReauth),
+		REG_AUTO_LOAD:   btoa(regs.AutoLoad)}
+
+	for _, reg := range []uint8{REG_CHANNEL, REG_PAN_ID, REG_AUTO_REAUTH, REG_AUTO_LOAD} {
+		if err := m.SetRegister(reg, vals[reg]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *module) Save() error {
+	return toError(m.ctrl.Send(Save()))
+}
+
+func (m *module) Load() error {
+	return toError(m.ctrl.Send(Load()))
+}
+
+func (m *module) Erase() error {
+	return toError(m.ctrl.Send(Erase()))
+}
+
+func toError(r Response) error {
+	if r == nil {
+		return errors.New("No response.")
+	}
+	switch r.Type() {
+	case FAIL:
+		return fmt.Errorf("Command failed: %s", r.(Fail).Code())
+	case DROPPED:
+		return errors.New("Command dropped.")
+	}
+	return nil
+}
+
+func btoa(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,251 @@
+package bp35a1
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+type lk int
+
+const (
+	SAMPLE lk = iota
+	DEGRADED
+	RECOVERED
+)
+
+type linkHandler func(LinkState)
+
+/* LinkState */
+type LinkState interface {
+	Time() time.Time
+	RTT() time.Duration
+	AvgRTT() time.Duration
+	Loss() float64
+	LQI() uint8
+	Degraded() bool
+}
+
+type linkState struct {
+	time     time.Time
+	rtt      time.Duration
+	avgrtt   time.Duration
+	loss     float64
+	lqi      uint8
+	degraded bool
+}
+
+func (s *linkState) Time() time.Time {
+	return s.time
+}
+
+func (s *linkState) RTT() time.Duration {
+	return s.rtt
+}
+
+func (s *linkState) AvgRTT() time.Duration {
+	return s.avgrtt
+}
+
+func (s *linkState) Loss() float64 {
+	return s.loss
+}
+
+func (s *linkState) LQI() uint8 {
+	return s.lqi
+}
+
+func (s *linkState) Degraded() bool {
+	return s.degraded
+}
+
+/* LinkMonitor */
+type MonitorConfig struct {
+	Interval time.Duration
+	Window   int
+	MaxLoss  float64
+	MaxRTT   time.Duration
+}
+
+type LinkMonitor interface {
+	Start()
+	Stop()
+	State() LinkState
+	RegisterHandler(lk, ...linkHandler)
+}
+
+type probe struct {
+	rtt time.Duration
+	ok  bool
+}
+
+type linkMonitor struct {
+	ctrl     Controller
+	ipaddr   net.IP
+	hwaddr   string
+	conf     MonitorConfig
+	handlers map[lk][]linkHandler
+	mutex    *sync.Mutex
+	probes   []probe
+	lqi      uint8
+	state    *linkState
+	stop     chan struct{}
+}
+
+func NewLinkMonitor(c Controller, hwaddr string, lqi uint8, ipaddr net.IP, conf *MonitorConfig) LinkMonitor {
+	m := &linkMonitor{
+		ctrl:     c,
+		ipaddr:   ipaddr,
+		hwaddr:   hwaddr,
+		conf:     *conf,
+		handlers: make(map[lk][]linkHandler),
+		mutex:    new(sync.Mutex),
+		probes:   []probe{},
+		lqi:      lqi,
+		state:    &linkState{lqi: lqi}}
+
+	if m.conf.Interval <= 0 {
+		m.conf.Interval = time.Minute
+	}
+	if m.conf.Window <= 0 {
+		m.conf.Window = 10
+	}
+
+	c.RegisterHandler(EPANDESC, func(e Event) {
+		p := e.(EventPanDesc)
+		if p.Addr() == m.hwaddr {
+			m.mutex.Lock()
+			m.lqi = p.LQI()
+			m.mutex.Unlock()
+		}
+	})
+
+	return m
+}
+
+func (m *linkMonitor) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	go m.run(m.stop)
+}
+
+func (m *linkMonitor) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+func (m *linkMonitor) State() LinkState {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s := *m.state
+	return &s
+}
+
+func (m *linkMonitor) RegisterHandler(l lk, hdr ...linkHandler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handlers[l] = append(m.handlers[l], hdr...)
+}
+
+func (m *linkMonitor) run(stop <-chan struct{}) {
+	t := time.NewTicker(m.conf.Interval)
+	defer t.Stop()
+
+	for {
+		if p, ok := m.ping(); ok {
+			m.update(p)
+		}
+
+		select {
+		case <-t.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ok is false when the ping was dropped from the queue, e.g. during a scan;
+// that is not a lost probe.
+func (m *linkMonitor) ping() (p probe, ok bool) {
+	c, err := Ping(m.ipaddr)
+	if err != nil {
+		return probe{}, true
+	}
+
+	start := time.Now()
+	r := m.ctrl.SendPriority(BACKGROUND, c,
+		func(e Event) bool {
+			if e.Type() == EPONG && e.(EventPong).Sender().Equal(m.ipaddr) {
+				p.rtt, p.ok = time.Since(start), true
+				return true
+			}
+			return false
+		})
+
+	if r != nil && r.Type() == DROPPED {
+		return probe{}, false
+	}
+	if r == nil || r.Type() != OK {
+		return probe{}, true
+	}
+	return p, true
+}
+
+func (m *linkMonitor) update(p probe) {
+	m.mutex.Lock()
+
+	m.probes = append(m.probes, p)
+	if len(m.probes) > m.conf.Window {
+		m.probes = m.probes[len(m.probes)-m.conf.Window:]
+	}
+
+	var lost int
+	var sum time.Duration
+	for _, v := range m.probes {
+		if v.ok {
+			sum += v.rtt
+		} else {
+			lost++
+		}
+	}
+
+	s := &linkState{
+		time: time.Now(),
+		rtt:  p.rtt,
+		loss: float64(lost) / float64(len(m.probes)),
+		lqi:  m.lqi}
+	if n := len(m.probes) - lost; n > 0 {
+		s.avgrtt = sum / time.Duration(n)
+	}
+	s.degraded = (m.conf.MaxLoss > 0 && s.loss > m.conf.MaxLoss) ||
+		(m.conf.MaxRTT > 0 && s.avgrtt > m.conf.MaxRTT) ||
+		s.avgrtt == 0
+
+	prev := m.state.degraded
+	m.state = s
+
+	hdr := append([]linkHandler{}, m.handlers[SAMPLE]...)
+	switch {
+	case s.degraded && !prev:
+		hdr = append(hdr, m.handlers[DEGRADED]...)
+	case !s.degraded && prev:
+		hdr = append(hdr, m.handlers[RECOVERED]...)
+	}
+	m.mutex.Unlock()
+
+	for _, h := range hdr {
+		h(s)
+	}
+}
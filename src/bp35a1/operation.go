@@ -0,0 +1,264 @@
+package bp35a1
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrOperationTimeout = errors.New("Operation timed out.")
+	ErrPanaFailed       = errors.New("PANA authentication failed.")
+	ErrCanceled         = errors.New("Operation canceled.")
+)
+
+/* Operation */
+type Operation interface {
+	Command() Command
+	Response() Response
+	Events() <-chan Event
+	Received() []Event
+	Wait(context.Context) error
+	Result() (Event, error)
+	Cancel()
+}
+
+type rule struct {
+	related   func(Event) bool
+	done      func(Event) (bool, error)
+	timeout   time.Duration
+	exclusive bool
+}
+
+type operation struct {
+	cmd      Command
+	resp     Response
+	rule     *rule
+	mutex    *sync.Mutex
+	events   chan Event
+	received []Event
+	result   Event
+	err      error
+	finished bool
+	done     chan struct{}
+}
+
+func (c *controller) Begin(cmd Command) Operation {
+	r := ruleFor(cmd)
+	if r == nil {
+		o := newOperation(cmd)
+		o.resp = c.SendPriority(CONTROL, cmd)
+		o.finish(nil, toError(o.resp))
+		return o
+	}
+
+	o := c.watch(cmd, r)
+	o.resp = c.SendPriority(CONTROL, cmd)
+	if err := toError(o.resp); err != nil {
+		o.finish(nil, err)
+	}
+	return o
+}
+
+func (c *controller) Expect(cond condition, timeout time.Duration) Operation {
+	return c.watch(nil, &rule{
+		related: cond,
+		done: func(Event) (bool, error) {
+			return true, nil
+		},
+		timeout: timeout})
+}
+
+func newOperation(cmd Command) *operation {
+	return &operation{
+		cmd:    cmd,
+		mutex:  new(sync.Mutex),
+		events: make(chan Event, 64),
+		done:   make(chan struct{})}
+}
+
+func (c *controller) watch(cmd Command, r *rule) *operation {
+	o := newOperation(cmd)
+	o.rule = r
+
+	if r.exclusive {
+		c.setBusy(1)
+	}
+
+	w := make(chan Event, 16)
+	c.addWatcher(w, nil)
+	go func() {
+		defer c.removeWatcher(w)
+		if r.exclusive {
+			defer c.setBusy(-1)
+		}
+
+		t := time.NewTimer(r.timeout)
+		defer t.Stop()
+
+		for {
+			select {
+			case e := <-w:
+				if !r.related(e) {
+					continue
+				}
+				o.add(e)
+				if ok, err := r.done(e); ok {
+					o.finish(e, err)
+					return
+				}
+			case <-t.C:
+				o.finish(nil, ErrOperationTimeout)
+				return
+			case <-o.done:
+				return
+			}
+		}
+	}()
+	return o
+}
+
+func (o *operation) Command() Command {
+	return o.cmd
+}
+
+func (o *operation) Response() Response {
+	return o.resp
+}
+
+func (o *operation) Events() <-chan Event {
+	return o.events
+}
+
+func (o *operation) Received() []Event {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]Event{}, o.received...)
+}
+
+func (o *operation) Wait(ctx context.Context) error {
+	select {
+	case <-o.done:
+		_, err := o.Result()
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (o *operation) Result() (Event, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.result, o.err
+}
+
+// Finishes the operation with ErrCanceled unless it is already finished,
+// which removes its watcher.
+func (o *operation) Cancel() {
+	o.finish(nil, ErrCanceled)
+}
+
+func (o *operation) add(e Event) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.finished {
+		return
+	}
+	o.received = append(o.received, e)
+	select {
+	case o.events <- e:
+	default:
+	}
+}
+
+func (o *operation) finish(e Event, err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.finished {
+		return
+	}
+	o.finished = true
+	o.result, o.err = e, err
+	close(o.events)
+	close(o.done)
+}
+
+func ruleFor(cmd Command) *rule {
+	switch c := cmd.(type) {
+	case *command_scan:
+		return &rule{
+			related: func(e Event) bool {
+				switch e.Type() {
+				case EPANDESC, EEDSCAN:
+					return true
+				case EVENT:
+					n := e.(EventEvent).Num()
+					return n == 0x20 || n == 0x22
+				}
+				return false
+			},
+			done: func(e Event) (bool, error) {
+				return isEvent(e, 0x22), nil
+			},
+			timeout:   scanTime(c.mask, c.duration) + time.Second*10,
+			exclusive: true}
+	case *command_join:
+		return joinRule()
+	case *command:
+		switch c.id {
+		case SKREJOIN:
+			return joinRule()
+		case SKTERM:
+			return &rule{
+				related: func(e Event) bool {
+					return isEvent(e, 0x27) || isEvent(e, 0x28)
+				},
+				done: func(e Event) (bool, error) {
+					return true, nil
+				},
+				timeout: time.Second * 30}
+		}
+	}
+	return nil
+}
+
+func joinRule() *rule {
+	return &rule{
+		related: func(e Event) bool {
+			if e.Type() == ERXUDP {
+				return e.(EventRxUDP).LPort() == 716
+			}
+			if e.Type() == EVENT {
+				n := e.(EventEvent).Num()
+				return n == 0x21 || n == 0x24 || n == 0x25
+			}
+			return false
+		},
+		done: func(e Event) (bool, error) {
+			switch {
+			case isEvent(e, 0x24):
+				return true, ErrPanaFailed
+			case isEvent(e, 0x25):
+				return true, nil
+			}
+			return false, nil
+		},
+		timeout:   time.Second * 60,
+		exclusive: true}
+}
+
+// Scan time per channel is about 9.6ms * (2^duration + 1).
+func scanTime(mask uint32, duration uint8) time.Duration {
+	var n time.Duration
+	for ; mask != 0; mask &= mask - 1 {
+		n++
+	}
+	return n * time.Microsecond * 9600 * time.Duration((1<<duration)+1)
+}
+
+func isEvent(e Event, num uint8) bool {
+	return e.Type() == EVENT && e.(EventEvent).Num() == num
+}
@@ -0,0 +1,108 @@
+package bp35a1
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+type pending struct {
+	cmd    Command
+	pr     pr
+	echo   string
+	echoed bool
+	queued time.Time
+	start  time.Time
+	resp   chan Response
+	sent   chan struct{} // closed when the command leaves the queue
+	done   chan struct{}
+}
+
+func newPending(cmd Command) *pending {
+	return &pending{
+		cmd:  cmd,
+		echo: echoText(cmd),
+		resp: make(chan Response, 1),
+		sent: make(chan struct{}),
+		done: make(chan struct{})}
+}
+
+// The text part of the command line; binary payloads are not compared.
+func echoText(c Command) string {
+	s := c.String()
+	for _, p := range c.Parameters() {
+		t, ok := p.(string)
+		if !ok {
+			break
+		}
+		s += " " + t
+	}
+	return s
+}
+
+func (p *pending) drop() {
+	p.resp <- &response{t: DROPPED}
+	close(p.done)
+}
+
+func (c *controller) begin(p *pending) {
+	c.pmutex.Lock()
+	defer c.pmutex.Unlock()
+	p.start = time.Now()
+	c.current = p
+	close(p.sent)
+}
+
+func (c *controller) complete(p *pending, r Response) bool {
+	c.pmutex.Lock()
+	defer c.pmutex.Unlock()
+
+	if c.current != p {
+		return false
+	}
+	c.current = nil
+
+	if r != nil {
+		c.stats.response(r.Type(), time.Since(p.start))
+		if r.Type() == OK {
+			if s, ok := p.cmd.(*command_sreg); ok && s.reg == REG_ECHO_BACK && s.val != "" {
+				c.echo = atoi(s.val) != 0
+			}
+		}
+	}
+
+	p.resp <- r
+	close(p.done)
+	return true
+}
+
+func (c *controller) echoed(data string) {
+	c.pmutex.Lock()
+	p := c.current
+	ok := p != nil && !p.echoed && strings.HasPrefix(data, p.echo)
+	if ok {
+		p.echoed = true
+	}
+	c.pmutex.Unlock()
+
+	if !ok {
+		c.unmatched(data)
+	}
+}
+
+func (c *controller) respond(r Response, data string) {
+	c.pmutex.Lock()
+	p := c.current
+	ok := p != nil && (p.echoed || !c.echo) && (r.Type() != RESULT || p.cmd.ExpectsResult())
+	c.pmutex.Unlock()
+
+	if !ok || !c.complete(p, r) {
+		c.unmatched(data)
+	}
+}
+
+func (c *controller) unmatched(data string) {
+	log.Debugf("Unmatched line: %s", Redact(data))
+	c.stats.unmatch()
+}
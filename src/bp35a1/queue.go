@@ -0,0 +1,94 @@
+package bp35a1
+
+import (
+	"sync"
+	"time"
+)
+
+type pr int
+
+const (
+	CONTROL pr = iota
+	INTERACTIVE
+	SCHEDULED
+	BACKGROUND
+)
+
+const numPriorities = int(BACKGROUND) + 1
+
+type queue struct {
+	mutex  *sync.Mutex
+	items  [numPriorities][]*pending
+	maxage [numPriorities]time.Duration
+	signal chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{
+		mutex:  new(sync.Mutex),
+		signal: make(chan struct{}, 1)}
+}
+
+func (q *queue) push(p *pending) {
+	q.mutex.Lock()
+	p.queued = time.Now()
+	q.items[p.pr] = append(q.items[p.pr], p)
+	q.mutex.Unlock()
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Blocks until a command is queued. Commands that are past their maximum
+// age, or low priority ones while busy() is true, are returned as dropped.
+func (q *queue) pop(busy func() bool) (p *pending, dropped []*pending) {
+	for {
+		q.mutex.Lock()
+		for i := range q.items {
+			for len(q.items[i]) > 0 {
+				p, q.items[i] = q.items[i][0], q.items[i][1:]
+				if q.stale(p, busy) {
+					dropped = append(dropped, p)
+					p = nil
+					continue
+				}
+				break
+			}
+			if p != nil || len(dropped) > 0 {
+				break
+			}
+		}
+		q.mutex.Unlock()
+
+		if p != nil || len(dropped) > 0 {
+			return p, dropped
+		}
+		<-q.signal
+	}
+}
+
+func (q *queue) stale(p *pending, busy func() bool) bool {
+	if age := q.maxage[p.pr]; age > 0 && time.Since(p.queued) > age {
+		return true
+	}
+	return p.pr >= SCHEDULED && busy()
+}
+
+func (q *queue) setMaxAge(p pr, age time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.maxage[p] = age
+}
+
+func (q *queue) depth() map[pr]int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	d := make(map[pr]int, numPriorities)
+	for i, v := range q.items {
+		d[pr(i)] = len(v)
+	}
+	return d
+}
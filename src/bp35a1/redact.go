@@ -0,0 +1,46 @@
+package bp35a1
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const MASK = "********"
+
+// Arguments of the commands that carry credentials or keys.
+var secretArgs = regexp.MustCompile(`(SKSETPWD\s+[0-9A-Fa-f]+\s+|SKSETRBID\s+|SKSETPSK\s+[0-9A-Fa-f]+\s+|SKSETKEY\s+[0-9A-Fa-f]+\s+)\S+`)
+
+var secrets = struct {
+	mutex  *sync.Mutex
+	values []string
+}{mutex: new(sync.Mutex)}
+
+// Registers a value to be masked by Redact. Values shorter than 4
+// characters are ignored, they would mask too much unrelated text.
+func AddSecret(s string) {
+	if len(s) < 4 {
+		return
+	}
+
+	secrets.mutex.Lock()
+	defer secrets.mutex.Unlock()
+	for _, v := range secrets.values {
+		if v == s {
+			return
+		}
+	}
+	secrets.values = append(secrets.values, s)
+}
+
+// Masks the arguments of credential commands and every registered secret.
+func Redact(s string) string {
+	s = secretArgs.ReplaceAllString(s, "${1}"+MASK)
+
+	secrets.mutex.Lock()
+	defer secrets.mutex.Unlock()
+	for _, v := range secrets.values {
+		s = strings.Replace(s, v, MASK, -1)
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package bp35a1
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactCommands(t *testing.T) {
+	key := []byte("0123456789ABCDEF")
+	mustCmd := func(c Command, err error) Command {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		cmd    Command
+		want   string
+		secret string
+	}{
+		{"SKSETPWD", mustCmd(SetPassword("PASSWORD0123")),
+			"SKSETPWD 0C " + MASK, "PASSWORD0123"},
+		{"SKSETRBID", mustCmd(SetRouteBId("00112233445566778899AABBCCDDEEFF")),
+			"SKSETRBID " + MASK, "00112233445566778899AABBCCDDEEFF"},
+		{"SKSETPSK", mustCmd(SetPSK(key)),
+			"SKSETPSK 10 " + MASK, "30313233343536373839414243444546"},
+		{"SKSETKEY", mustCmd(SetKey(1, key)),
+			"SKSETKEY 01 " + MASK, "30313233343536373839414243444546"},
+	}
+
+	for _, tt := range tests {
+		got := Redact(echoText(tt.cmd))
+		if got != tt.want {
+			t.Errorf("%s: Redact() = %q, want %q", tt.name, got, tt.want)
+		}
+		if strings.Contains(got, tt.secret) {
+			t.Errorf("%s: secret in %q", tt.name, got)
+		}
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	AddSecret("s3cr3t-value")
+	AddSecret("abc")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"fail line", "FAIL ER10 s3cr3t-value", "FAIL ER10 " + MASK},
+		{"config dump", "{Password:s3cr3t-value Device:/dev/ttyUSB0}",
+			"{Password:" + MASK + " Device:/dev/ttyUSB0}"},
+		{"repeated", "s3cr3t-value s3cr3t-value", MASK + " " + MASK},
+		{"short secret", "abc abcdef", "abc abcdef"},
+		{"no secret", "EVENT 21 FE80:0000:0000:0000:0000:0000:0000:0001 00",
+			"EVENT 21 FE80:0000:0000:0000:0000:0000:0000:0001 00"},
+	}
+
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("%s: Redact(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
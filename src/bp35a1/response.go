@@ -6,6 +6,7 @@ const (
 	OK rp = iota
 	FAIL
 	RESULT
+	DROPPED // Dropped from the queue without being sent
 )
 
 /* Response */
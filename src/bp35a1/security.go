@@ -0,0 +1,97 @@
+package bp35a1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+)
+
+type Peer struct {
+	IpAddr net.IP
+	HwAddr string
+}
+
+func NewPeer(hwaddr string) (Peer, error) {
+	b, err := hex.DecodeString(hwaddr)
+	if err != nil || len(b) != 8 {
+		return Peer{}, fmt.Errorf("Invalid MAC address: %s", hwaddr)
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	ip[0], ip[1] = 0xfe, 0x80
+	copy(ip[8:], b)
+	ip[8] ^= 0x02
+
+	return Peer{IpAddr: ip, HwAddr: hwaddr}, nil
+}
+
+type ManualSecurity interface {
+	SetKey(uint8, []byte) error
+	RemoveKey(uint8) error
+	AddPeer(Peer) error
+	RemovePeer(Peer) error
+	Setup(uint8, uint16, uint8, []byte, ...Peer) error
+}
+
+type manualSecurity struct {
+	ctrl Controller
+}
+
+func NewManualSecurity(c Controller) ManualSecurity {
+	return &manualSecurity{ctrl: c}
+}
+
+func (s *manualSecurity) SetKey(index uint8, key []byte) error {
+	c, err := SetKey(index, key)
+	if err != nil {
+		return err
+	}
+	return toError(s.ctrl.Send(c))
+}
+
+func (s *manualSecurity) RemoveKey(index uint8) error {
+	return toError(s.ctrl.Send(RmKey(index)))
+}
+
+func (s *manualSecurity) AddPeer(p Peer) error {
+	c, err := AddNbr(p.IpAddr, p.HwAddr)
+	if err != nil {
+		return err
+	}
+	if err := toError(s.ctrl.Send(c)); err != nil {
+		return err
+	}
+	return s.secEnable(true, p)
+}
+
+func (s *manualSecurity) RemovePeer(p Peer) error {
+	return s.secEnable(false, p)
+}
+
+func (s *manualSecurity) secEnable(enable bool, p Peer) error {
+	c, err := SecEnable(enable, p.IpAddr, p.HwAddr)
+	if err != nil {
+		return err
+	}
+	return toError(s.ctrl.Send(c))
+}
+
+func (s *manualSecurity) Setup(channel uint8, panid uint16, index uint8, key []byte, peers ...Peer) error {
+	m := NewModule(s.ctrl)
+	if err := m.SetRegister(REG_CHANNEL, fmt.Sprintf("%02X", channel)); err != nil {
+		return err
+	}
+	if err := m.SetRegister(REG_PAN_ID, fmt.Sprintf("%04X", panid)); err != nil {
+		return err
+	}
+
+	if err := s.SetKey(index, key); err != nil {
+		return err
+	}
+	for _, p := range peers {
+		if err := s.AddPeer(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package bp35a1
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	PORT_PANA    uint16 = 716
+	PORT_ECHONET uint16 = 3610
+)
+
+type udpHandler func(EventRxUDP)
+
+type service struct {
+	handle   uint8
+	opened   bool
+	handlers []udpHandler
+}
+
+func (c *controller) RegisterService(port uint16, hdr ...udpHandler) error {
+	c.regmutex.Lock()
+	defer c.regmutex.Unlock()
+
+	if c.addHandlers(port, hdr) {
+		return nil
+	}
+
+	ports, err := c.udpPorts()
+	if err != nil {
+		return err
+	}
+
+	s := &service{handlers: hdr}
+	for i, p := range ports {
+		if p == port {
+			s.handle = uint8(i + 1)
+			break
+		}
+	}
+	if s.handle == 0 {
+		for i, p := range ports {
+			if p == 0 {
+				s.handle = uint8(i + 1)
+				break
+			}
+		}
+		if s.handle == 0 {
+			return fmt.Errorf("No free UDP handle for port %d.", port)
+		}
+
+		cmd, err := UdpPort(s.handle, port)
+		if err != nil {
+			return err
+		}
+		if err := toError(c.Send(cmd)); err != nil {
+			return err
+		}
+		s.opened = true
+	}
+
+	c.smutex.Lock()
+	defer c.smutex.Unlock()
+	c.services[port] = s
+	return nil
+}
+
+func (c *controller) UnregisterService(port uint16) error {
+	c.regmutex.Lock()
+	defer c.regmutex.Unlock()
+
+	c.smutex.Lock()
+	s, ok := c.services[port]
+	delete(c.services, port)
+	c.smutex.Unlock()
+
+	if ok && s.opened {
+		cmd, err := UdpPort(s.handle, 0)
+		if err != nil {
+			return err
+		}
+		return toError(c.Send(cmd))
+	}
+	return nil
+}
+
+func (c *controller) addHandlers(port uint16, hdr []udpHandler) bool {
+	c.smutex.Lock()
+	defer c.smutex.Unlock()
+
+	s, ok := c.services[port]
+	if ok {
+		s.handlers = append(s.handlers, hdr...)
+	}
+	return ok
+}
+
+func (c *controller) udpPorts() ([6]uint16, error) {
+	var ports *[6]uint16
+	r := c.Send(Table(0x0E),
+		func(e Event) bool {
+			if e.Type() == EPORT {
+				p := e.(EventPort).UdpPorts()
+				ports = &p
+				return true
+			}
+			return false
+		})
+	if err := toError(r); err != nil {
+		return [6]uint16{}, err
+	}
+	if ports == nil {
+		return [6]uint16{}, errors.New("No UDP port table.")
+	}
+	return *ports, nil
+}
+
+func (c *controller) dispatch(e EventRxUDP) {
+	c.smutex.Lock()
+	s, ok := c.services[e.LPort()]
+	var hdr []udpHandler
+	if ok {
+		hdr = append(hdr, s.handlers...)
+	}
+	c.smutex.Unlock()
+
+	if !ok {
+		c.stats.unhandled(e.LPort())
+		return
+	}
+	for _, h := range hdr {
+		h(e)
+	}
+}
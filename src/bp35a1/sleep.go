@@ -0,0 +1,75 @@
+package bp35a1
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+var wakeupChar = []byte("\r\n")
+
+const wakeupTimeout = time.Second * 5
+
+func (c *controller) Sleep() error {
+	if c.Sleeping() {
+		return nil
+	}
+
+	if err := toError(c.Send(DSleep())); err != nil {
+		return err
+	}
+
+	c.slmutex.Lock()
+	defer c.slmutex.Unlock()
+	c.sleeping = true
+	c.wakeup = make(chan struct{})
+	log.Debug("Module is sleeping")
+	return nil
+}
+
+func (c *controller) Wake() error {
+	c.slmutex.Lock()
+	if !c.sleeping {
+		c.slmutex.Unlock()
+		return nil
+	}
+	wakeup := c.wakeup
+	c.slmutex.Unlock()
+
+	if _, err := c.write(c.port, wakeupChar); err != nil {
+		return err
+	}
+
+	select {
+	case <-wakeup:
+		log.Debug("Module woke up")
+		return nil
+	case <-time.After(wakeupTimeout):
+		c.awake()
+		return errors.New("Module did not wake up.")
+	}
+}
+
+func (c *controller) Sleeping() bool {
+	c.slmutex.Lock()
+	defer c.slmutex.Unlock()
+	return c.sleeping
+}
+
+func (c *controller) awake() {
+	c.slmutex.Lock()
+	defer c.slmutex.Unlock()
+
+	if c.sleeping {
+		c.sleeping = false
+		close(c.wakeup)
+	}
+}
+
+func (c *controller) write(wt io.Writer, b []byte) (int, error) {
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+	return wt.Write(b)
+}
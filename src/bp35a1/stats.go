@@ -0,0 +1,261 @@
+package bp35a1
+
+import (
+	"sync"
+	"time"
+)
+
+var latencyBuckets = []time.Duration{
+	time.Millisecond * 10,
+	time.Millisecond * 50,
+	time.Millisecond * 100,
+	time.Millisecond * 250,
+	time.Millisecond * 500,
+	time.Second,
+	time.Second * 2,
+	time.Second * 5}
+
+/* Histogram */
+type Histogram interface {
+	Count() uint64
+	Sum() time.Duration
+	Buckets() []time.Duration
+	Counts() []uint64
+}
+
+type histogram struct {
+	count  uint64
+	sum    time.Duration
+	counts []uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	h.count++
+	h.sum += d
+	for i, b := range latencyBuckets {
+		if d <= b {
+			h.counts[i]++
+			return
+		}
+	}
+	h.counts[len(latencyBuckets)]++
+}
+
+func (h *histogram) Count() uint64 {
+	return h.count
+}
+
+func (h *histogram) Sum() time.Duration {
+	return h.sum
+}
+
+// Upper bounds of Counts(); the last count has no upper bound.
+func (h *histogram) Buckets() []time.Duration {
+	return append([]time.Duration{}, latencyBuckets...)
+}
+
+func (h *histogram) Counts() []uint64 {
+	return append([]uint64{}, h.counts...)
+}
+
+/* Stats */
+type Stats interface {
+	Commands() map[string]uint64
+	Responses() map[rp]uint64
+	Events() map[ev]uint64
+	Timeouts() uint64
+	Malformed() uint64
+	Unmatched() uint64
+	Dropped() uint64
+	Stalls() uint64
+	UnhandledUDP() map[uint16]uint64
+	QueueDepth() map[pr]int
+	BytesIn() uint64
+	BytesOut() uint64
+	Latency() Histogram
+}
+
+type stats struct {
+	mutex     *sync.Mutex
+	commands  map[string]uint64
+	responses map[rp]uint64
+	events    map[ev]uint64
+	timeouts  uint64
+	malformed uint64
+	unmatched uint64
+	dropped   uint64
+	stalls    uint64
+	udp       map[uint16]uint64
+	depth     map[pr]int
+	bytesin   uint64
+	bytesout  uint64
+	latency   *histogram
+}
+
+func newStats() *stats {
+	return &stats{
+		mutex:     new(sync.Mutex),
+		commands:  make(map[string]uint64),
+		responses: make(map[rp]uint64),
+		events:    make(map[ev]uint64),
+		udp:       make(map[uint16]uint64),
+		latency:   newHistogram()}
+}
+
+func (s *stats) Commands() map[string]uint64 {
+	m := make(map[string]uint64, len(s.commands))
+	for k, v := range s.commands {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *stats) Responses() map[rp]uint64 {
+	m := make(map[rp]uint64, len(s.responses))
+	for k, v := range s.responses {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *stats) Events() map[ev]uint64 {
+	m := make(map[ev]uint64, len(s.events))
+	for k, v := range s.events {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *stats) Timeouts() uint64 {
+	return s.timeouts
+}
+
+func (s *stats) Malformed() uint64 {
+	return s.malformed
+}
+
+func (s *stats) Unmatched() uint64 {
+	return s.unmatched
+}
+
+func (s *stats) Dropped() uint64 {
+	return s.dropped
+}
+
+// Serial reads that timed out without data.
+func (s *stats) Stalls() uint64 {
+	return s.stalls
+}
+
+func (s *stats) UnhandledUDP() map[uint16]uint64 {
+	m := make(map[uint16]uint64, len(s.udp))
+	for k, v := range s.udp {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *stats) QueueDepth() map[pr]int {
+	m := make(map[pr]int, len(s.depth))
+	for k, v := range s.depth {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *stats) BytesIn() uint64 {
+	return s.bytesin
+}
+
+func (s *stats) BytesOut() uint64 {
+	return s.bytesout
+}
+
+func (s *stats) Latency() Histogram {
+	return s.latency
+}
+
+func (s *stats) snapshot() *stats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return &stats{
+		commands:  s.Commands(),
+		responses: s.Responses(),
+		events:    s.Events(),
+		timeouts:  s.timeouts,
+		malformed: s.malformed,
+		unmatched: s.unmatched,
+		dropped:   s.dropped,
+		stalls:    s.stalls,
+		udp:       s.UnhandledUDP(),
+		bytesin:   s.bytesin,
+		bytesout:  s.bytesout,
+		latency: &histogram{
+			count:  s.latency.count,
+			sum:    s.latency.sum,
+			counts: s.latency.Counts()}}
+}
+
+func (s *stats) command(c Command, n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.commands[c.String()]++
+	s.bytesout += uint64(n)
+}
+
+func (s *stats) response(t rp, d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.responses[t]++
+	s.latency.observe(d)
+}
+
+func (s *stats) timeout() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.timeouts++
+}
+
+func (s *stats) unmatch() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unmatched++
+}
+
+func (s *stats) drop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.dropped++
+}
+
+func (s *stats) stall() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stalls++
+}
+
+func (s *stats) unhandled(port uint16) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.udp[port]++
+}
+
+func (s *stats) event(t ev) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events[t]++
+}
+
+func (s *stats) line(n int, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bytesin += uint64(n)
+	if !ok {
+		s.malformed++
+	}
+}
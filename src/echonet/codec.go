@@ -0,0 +1,179 @@
+package echonet
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+/* Codec */
+type Codec interface {
+	Decode([]byte) (interface{}, error)
+	Encode(interface{}) ([]byte, error)
+}
+
+type codec struct {
+	decode func([]byte) (interface{}, error)
+	encode func(interface{}) ([]byte, error)
+}
+
+func (c *codec) Decode(b []byte) (interface{}, error) {
+	return c.decode(b)
+}
+
+func (c *codec) Encode(v interface{}) ([]byte, error) {
+	if c.encode == nil {
+		return nil, ErrNoCodec
+	}
+	return c.encode(v)
+}
+
+type codecKey struct {
+	class Class
+	epc   Epc
+}
+
+var codecs = make(map[codecKey]Codec)
+
+func register(class Class, epc Epc, c Codec) {
+	codecs[codecKey{class, epc}] = c
+}
+
+// Codecs of the class take precedence over the super class ones.
+func CodecFor(class Class, epc Epc) (Codec, bool) {
+	if c, ok := codecs[codecKey{class, epc}]; ok {
+		return c, true
+	}
+	c, ok := superCodecs[epc]
+	return c, ok
+}
+
+func DecodeValue(class Class, p Property) (interface{}, error) {
+	c, ok := CodecFor(class, p.Epc())
+	if !ok {
+		return nil, ErrNoCodec
+	}
+	v, err := c.Decode(p.Edt())
+	return v, withClass(err, class)
+}
+
+func EncodeValue(class Class, epc Epc, v interface{}) (Property, error) {
+	c, ok := CodecFor(class, epc)
+	if !ok {
+		return nil, ErrNoCodec
+	}
+	b, err := c.Encode(v)
+	if err != nil {
+		return nil, withClass(err, class)
+	}
+	p := NewProperty()
+	p.SetEpc(epc)
+	p.SetPdc(byte(len(b)))
+	p.SetEdt(b)
+	return p, nil
+}
+
+func checkLen(epc Epc, b []byte, n int) error {
+	if len(b) != n {
+		return &LengthError{Epc: epc, Need: n, Have: len(b)}
+	}
+	return nil
+}
+
+// Unsigned integers of 1, 2 or 4 bytes, returned as uint8, uint16 or uint32.
+func uintCodec(epc Epc, size int, min, max uint32) Codec {
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, size); err != nil {
+				return nil, err
+			}
+			var v uint32
+			switch size {
+			case 1:
+				v = uint32(b[0])
+			case 2:
+				v = uint32(binary.BigEndian.Uint16(b))
+			case 4:
+				v = binary.BigEndian.Uint32(b)
+			}
+			if v < min || v > max {
+				return nil, &RangeError{Epc: epc, Value: v}
+			}
+			switch size {
+			case 1:
+				return uint8(v), nil
+			case 2:
+				return uint16(v), nil
+			}
+			return v, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			v, ok := toUint(i)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if v < min || v > max {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, v)
+			return b[4-size:], nil
+		}}
+}
+
+// Unsigned integer with the range given by the registry, or the full range
+// of the size if the property is not ranged there.
+func rangedUintCodec(class Class, epc Epc, size int) Codec {
+	min, max := uint32(0), uint32(1<<(8*uint64(size))-1)
+	if p, ok := LookupProperty(class, epc); ok && p.Ranged {
+		min, max = uint32(p.Min), uint32(p.Max)
+	}
+	return uintCodec(epc, size, min, max)
+}
+
+func toUint(i interface{}) (uint32, bool) {
+	switch v := i.(type) {
+	case uint8:
+		return uint32(v), true
+	case uint16:
+		return uint32(v), true
+	case uint32:
+		return v, true
+	case int:
+		if v >= 0 && int64(v) <= 0xFFFFFFFF {
+			return uint32(v), true
+		}
+	}
+	return 0, false
+}
+
+// YYYY MM DD hh mm [ss]
+func decodeTime(b []byte, seconds bool) time.Time {
+	s := 0
+	if seconds {
+		s = int(b[6])
+	}
+	return time.Date(
+		int(binary.BigEndian.Uint16(b[0:2])), time.Month(b[2]), int(b[3]),
+		int(b[4]), int(b[5]), s, 0, time.Local)
+}
+
+func encodeTime(t time.Time, seconds bool) []byte {
+	t = t.In(time.Local)
+	b := make([]byte, 6, 7)
+	binary.BigEndian.PutUint16(b[0:2], uint16(t.Year()))
+	b[2] = byte(t.Month())
+	b[3] = byte(t.Day())
+	b[4] = byte(t.Hour())
+	b[5] = byte(t.Minute())
+	if seconds {
+		b = append(b, byte(t.Second()))
+	}
+	return b
+}
+
+func validTime(b []byte, seconds bool) bool {
+	if b[2] < 1 || b[2] > 12 || b[3] < 1 || b[3] > 31 || b[4] > 23 || b[5] > 59 {
+		return false
+	}
+	return !seconds || b[6] <= 59
+}
@@ -6,6 +6,13 @@ import (
 	"io"
 )
 
+type Ehd uint16
+
+const (
+	EHD_FORMAT1 Ehd = 0x1081 // Specified message format
+	EHD_FORMAT2 Ehd = 0x1082 // Arbitrary message format
+)
+
 type Esv byte
 
 const (
@@ -33,8 +40,15 @@ type Class uint16
 const (
 	CLASS_SMART_EE_METER Class = 0x0288
 	CLASS_CONTROLLER     Class = 0x05FF
+	CLASS_NODE_PROFILE   Class = 0x0EF0
 )
 
+// ECHONET object: class and instance.
+type Eoj struct {
+	Class    Class
+	Instance uint8
+}
+
 type Epc byte
 
 const (
@@ -72,24 +86,33 @@ type Encoder interface {
 }
 
 type Decoder interface {
-	Decode([]byte) Frame
+	Decode([]byte) (Frame, error)
 }
 
 type Frame interface {
 	Encoder
 	Decoder
 
+	Header() Ehd
+	Tid() uint16
 	Seoj() (Class, uint8)
 	Deoj() (Class, uint8)
 	Esv() Esv
 	Opc() uint8
 	Properties() []Property
+	OpcGet() uint8
+	GetProperties() []Property
+	Edata() []byte
 
+	SetHeader(Ehd)
 	SetSeoj(Class, uint8)
 	SetDeoj(Class, uint8)
 	SetEsv(Esv)
 	SetOpc(uint8)
 	SetProperties([]Property)
+	SetOpcGet(uint8)
+	SetGetProperties([]Property)
+	SetEdata([]byte)
 }
 
 type Property interface {
@@ -137,24 +160,40 @@ func (p *property) SetEdt(data []byte) {
 }
 
 type frame struct {
-	seoj  Class
-	seoji uint8
-	deoj  Class
-	deoji uint8
-	esv   Esv
-	opc   uint8
-	props []*property
+	ehd    Ehd
+	tid    uint16
+	seoj   Class
+	seoji  uint8
+	deoj   Class
+	deoji  uint8
+	esv    Esv
+	opc    uint8
+	props  []*property
+	opcg   uint8       // SetGet only
+	gprops []*property // SetGet only
+	edata  []byte      // EHD_FORMAT2 only
 }
 
-func NewFrame() Frame {
-	return &frame{}
+// SetGet frames carry a second property block for the Get part.
+func (e Esv) setGet() bool {
+	return e == ESV_SET_GET || e == ESV_SET_GET_RES || e == ESV_SET_GET_SNA
 }
 
-var header = []byte{0x10, 0x81}
+func NewFrame() Frame {
+	return &frame{ehd: EHD_FORMAT1}
+}
 
 func (f *frame) Encode(t uint16) []byte {
-	buf := bytes.NewBuffer(header)
-	write(buf, t)
+	f.tid = t
+
+	buf := new(bytes.Buffer)
+	write(buf, f.ehd)
+	write(buf, f.tid)
+
+	if f.ehd == EHD_FORMAT2 {
+		buf.Write(f.edata)
+		return buf.Bytes()
+	}
 
 	write(buf, f.seoj)
 	write(buf, f.seoji)
@@ -164,38 +203,98 @@ func (f *frame) Encode(t uint16) []byte {
 	write(buf, f.esv)
 	write(buf, f.opc)
 
-	for i := 0; i < int(f.opc); i++ {
-		write(buf, f.props[i].epc)
-		write(buf, f.props[i].pdc)
-		if f.props[i].pdc > 0 {
-			write(buf, f.props[i].edt)
-		}
+	writeProperties(buf, f.opc, f.props)
+	if f.esv.setGet() {
+		write(buf, f.opcg)
+		writeProperties(buf, f.opcg, f.gprops)
 	}
 
 	return buf.Bytes()
 }
 
-func (f *frame) Decode(b []byte) Frame {
-	buf := bytes.NewBuffer(b)
+func writeProperties(buf *bytes.Buffer, opc uint8, props []*property) {
+	for i := 0; i < int(opc); i++ {
+		write(buf, props[i].epc)
+		write(buf, props[i].pdc)
+		if props[i].pdc > 0 {
+			write(buf, props[i].edt)
+		}
+	}
+}
 
-	buf.Next(4) // Header
+func Decode(b []byte) (Frame, error) {
+	return NewFrame().Decode(b)
+}
+
+func (f *frame) Decode(b []byte) (Frame, error) {
+	d := &decoder{buf: b}
 
-	read(buf, &f.seoj)
-	read(buf, &f.seoji)
-	read(buf, &f.deoj)
-	read(buf, &f.deoji)
+	ehd := d.next("EHD", 2)
+	tid := d.next("TID", 2)
+	if d.err != nil {
+		return nil, d.err
+	}
+	switch Ehd(binary.BigEndian.Uint16(ehd)) {
+	case EHD_FORMAT1:
+	case EHD_FORMAT2:
+		*f = frame{
+			ehd:   EHD_FORMAT2,
+			tid:   binary.BigEndian.Uint16(tid),
+			edata: d.buf[d.off:]}
+		return f, nil
+	default:
+		return nil, &HeaderError{Ehd1: ehd[0], Ehd2: ehd[1]}
+	}
 
-	read(buf, &f.esv)
-	read(buf, &f.opc)
+	seoj := d.next("SEOJ", 3)
+	deoj := d.next("DEOJ", 3)
+	esv := d.next("ESV", 1)
+	opc := d.next("OPC", 1)
+	if d.err != nil {
+		return nil, d.err
+	}
 
-	f.props = make([]*property, f.opc)
-	for i := 0; i < int(f.opc); i++ {
-		f.props[i] = &property{}
-		read(buf, &f.props[i].epc)
-		read(buf, &f.props[i].pdc)
-		f.props[i].edt = buf.Next(int(f.props[i].pdc))
+	props, err := d.properties(opc[0])
+	if err != nil {
+		return nil, err
+	}
+	var opcg uint8
+	var gprops []*property
+	if Esv(esv[0]).setGet() {
+		b := d.next("OPCGet", 1)
+		if d.err != nil {
+			return nil, d.err
+		}
+		opcg = b[0]
+		if gprops, err = d.properties(opcg); err != nil {
+			return nil, err
+		}
+	}
+	if d.off < len(d.buf) {
+		return nil, &TrailingError{Offset: d.off, Count: len(d.buf) - d.off}
 	}
-	return f
+
+	f.ehd = Ehd(binary.BigEndian.Uint16(ehd))
+	f.tid = binary.BigEndian.Uint16(tid)
+	f.seoj = Class(binary.BigEndian.Uint16(seoj))
+	f.seoji = seoj[2]
+	f.deoj = Class(binary.BigEndian.Uint16(deoj))
+	f.deoji = deoj[2]
+	f.esv = Esv(esv[0])
+	f.opc = opc[0]
+	f.props = props
+	f.opcg = opcg
+	f.gprops = gprops
+	f.edata = nil
+	return f, nil
+}
+
+func (f *frame) Header() Ehd {
+	return f.ehd
+}
+
+func (f *frame) Tid() uint16 {
+	return f.tid
 }
 
 func (f *frame) Seoj() (Class, uint8) {
@@ -222,6 +321,26 @@ func (f *frame) Properties() []Property {
 	return p
 }
 
+func (f *frame) OpcGet() uint8 {
+	return f.opcg
+}
+
+func (f *frame) GetProperties() []Property {
+	p := make([]Property, len(f.gprops))
+	for i, v := range f.gprops {
+		p[i] = v
+	}
+	return p
+}
+
+func (f *frame) Edata() []byte {
+	return f.edata
+}
+
+func (f *frame) SetHeader(ehd Ehd) {
+	f.ehd = ehd
+}
+
 func (f *frame) SetSeoj(class Class, index uint8) {
 	f.seoj = class
 	f.seoji = index
@@ -248,8 +367,20 @@ func (f *frame) SetProperties(props []Property) {
 	f.props = p
 }
 
-func read(r io.Reader, data interface{}) error {
-	return binary.Read(r, binary.BigEndian, data)
+func (f *frame) SetOpcGet(n uint8) {
+	f.opcg = n
+}
+
+func (f *frame) SetGetProperties(props []Property) {
+	p := make([]*property, len(props))
+	for i, v := range props {
+		p[i] = v.(*property)
+	}
+	f.gprops = p
+}
+
+func (f *frame) SetEdata(data []byte) {
+	f.edata = data
 }
 
 func write(w io.Writer, data interface{}) error {
@@ -0,0 +1,80 @@
+package echonet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// EHD, TID 0x0001, SEOJ 0x0288 01, DEOJ 0x05FF 01
+var head = []byte{0x10, 0x81, 0x00, 0x01, 0x02, 0x88, 0x01, 0x05, 0xFF, 0x01}
+
+func frameBytes(parts ...[]byte) []byte {
+	return bytes.Join(append([][]byte{head}, parts...), nil)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{"empty", nil,
+			&TruncatedError{Field: "EHD", Offset: 0, Need: 2, Have: 0}},
+		{"header", []byte{0x10, 0x80, 0x00, 0x01},
+			&HeaderError{Ehd1: 0x10, Ehd2: 0x80}},
+		{"no TID", []byte{0x10, 0x81, 0x00},
+			&TruncatedError{Field: "TID", Offset: 2, Need: 2, Have: 1}},
+		{"no ESV", head,
+			&TruncatedError{Field: "ESV", Offset: 10, Need: 1, Have: 0}},
+		{"no PDC", frameBytes([]byte{0x72, 0x01, 0xE7}),
+			&TruncatedError{Field: "PDC", Offset: 13, Need: 1, Have: 0}},
+		{"PDC past buffer", frameBytes([]byte{0x72, 0x01, 0xE7, 0x04, 0x00, 0x00}),
+			&TruncatedError{Field: "EDT", Offset: 14, Need: 4, Have: 2}},
+		{"count", frameBytes([]byte{0x72, 0x02, 0xE7, 0x01, 0x00}),
+			&CountError{Opc: 2, Count: 1}},
+		{"trailing", frameBytes([]byte{0x72, 0x01, 0xE7, 0x01, 0x00, 0xFF, 0xFF}),
+			&TrailingError{Offset: 15, Count: 2}},
+		{"no OPCGet", frameBytes([]byte{0x7E, 0x01, 0x80, 0x00}),
+			&TruncatedError{Field: "OPCGet", Offset: 14, Need: 1, Have: 0}},
+		{"SetGet count", frameBytes([]byte{0x7E, 0x00, 0x02, 0xE7, 0x01, 0x00}),
+			&CountError{Opc: 2, Count: 1}},
+	}
+
+	for _, tt := range tests {
+		f, err := Decode(tt.in)
+		if f != nil || !reflect.DeepEqual(err, tt.want) {
+			t.Errorf("%s: Decode() = %v, %#v, want %#v", tt.name, f, err, tt.want)
+		}
+	}
+}
+
+func TestDecodeSetGet(t *testing.T) {
+	in := frameBytes([]byte{0x7E, 0x01, 0x80, 0x00, 0x01, 0xE7, 0x04, 0x00, 0x00, 0x01, 0xF4})
+	f, err := Decode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Opc() != 1 || f.Properties()[0].Epc() != EPC_OPERATION_STATUS {
+		t.Errorf("Set block = %d %v", f.Opc(), f.Properties())
+	}
+	if f.OpcGet() != 1 || len(f.GetProperties()) != 1 {
+		t.Fatalf("Get block = %d %v", f.OpcGet(), f.GetProperties())
+	}
+	if p := f.GetProperties()[0]; p.Epc() != EPC_0288_INST_EE || !bytes.Equal(p.Edt(), []byte{0x00, 0x00, 0x01, 0xF4}) {
+		t.Errorf("Get property = %02X %X", byte(p.Epc()), p.Edt())
+	}
+	if out := f.Encode(f.Tid()); !bytes.Equal(out, in) {
+		t.Errorf("Encode() = %X, want %X", out, in)
+	}
+}
+
+func TestDecodeFormat2(t *testing.T) {
+	f, err := Decode([]byte{0x10, 0x82, 0x12, 0x34, 0xAA, 0xBB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Header() != EHD_FORMAT2 || f.Tid() != 0x1234 || !bytes.Equal(f.Edata(), []byte{0xAA, 0xBB}) {
+		t.Errorf("Decode() = %04X %04X %X", f.Header(), f.Tid(), f.Edata())
+	}
+}
@@ -0,0 +1,144 @@
+package echonet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The frame does not start with a supported EHD1/EHD2.
+type HeaderError struct {
+	Ehd1 byte
+	Ehd2 byte
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("Unsupported header: EHD1=%02X EHD2=%02X.", e.Ehd1, e.Ehd2)
+}
+
+// The frame ends in the middle of a field.
+type TruncatedError struct {
+	Field  string
+	Offset int
+	Need   int
+	Have   int
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("Frame truncated in %s at offset %d: need %d bytes, have %d.", e.Field, e.Offset, e.Need, e.Have)
+}
+
+// The frame ends cleanly after fewer properties than OPC announces.
+type CountError struct {
+	Opc   uint8
+	Count int
+}
+
+func (e *CountError) Error() string {
+	return fmt.Sprintf("OPC is %d but the frame holds %d properties.", e.Opc, e.Count)
+}
+
+// Bytes are left over after the last property.
+type TrailingError struct {
+	Offset int
+	Count  int
+}
+
+func (e *TrailingError) Error() string {
+	return fmt.Sprintf("%d trailing bytes at offset %d.", e.Count, e.Offset)
+}
+
+type decoder struct {
+	buf []byte
+	off int
+	err error
+}
+
+// Returns the next n bytes, or nil and records a TruncatedError.
+// Once an error is recorded every later call returns nil.
+func (d *decoder) next(field string, n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if d.off+n > len(d.buf) {
+		d.err = &TruncatedError{Field: field, Offset: d.off, Need: n, Have: len(d.buf) - d.off}
+		return nil
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+// Reads a block of opc properties.
+func (d *decoder) properties(opc uint8) ([]*property, error) {
+	props := make([]*property, opc)
+	for i := range props {
+		if d.off == len(d.buf) {
+			return nil, &CountError{Opc: opc, Count: i}
+		}
+		epc := d.next("EPC", 1)
+		pdc := d.next("PDC", 1)
+		if d.err != nil {
+			return nil, d.err
+		}
+		edt := d.next("EDT", int(pdc[0]))
+		if d.err != nil {
+			return nil, d.err
+		}
+		props[i] = &property{epc: Epc(epc[0]), pdc: pdc[0], edt: edt}
+	}
+	return props, nil
+}
+
+var (
+	ErrOverflow  = errors.New("Value overflow.")
+	ErrUnderflow = errors.New("Value underflow.")
+	ErrNoData    = errors.New("No data.")
+	ErrNoCodec   = errors.New("No codec for the property.")
+)
+
+// The EDT length does not fit the property. Class is set by DecodeValue
+// and EncodeValue, and names the property in the message.
+type LengthError struct {
+	Class Class
+	Epc   Epc
+	Need  int
+	Have  int
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("%s: need %d bytes, have %d.", PropertyName(e.Class, e.Epc), e.Need, e.Have)
+}
+
+// The value is outside the range defined for the property.
+type RangeError struct {
+	Class Class
+	Epc   Epc
+	Value interface{}
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("%s: value %v out of range.", PropertyName(e.Class, e.Epc), e.Value)
+}
+
+// The value passed to Encode has the wrong type for the property.
+type TypeError struct {
+	Class Class
+	Epc   Epc
+	Value interface{}
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%s: cannot encode %T.", PropertyName(e.Class, e.Epc), e.Value)
+}
+
+func withClass(err error, class Class) error {
+	switch e := err.(type) {
+	case *LengthError:
+		e.Class = class
+	case *RangeError:
+		e.Class = class
+	case *TypeError:
+		e.Class = class
+	}
+	return err
+}
@@ -0,0 +1,405 @@
+package echonet
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Unit of the cumulative amounts (0xE1).
+type Unit byte
+
+const (
+	UNIT_1KWH      Unit = 0x00
+	UNIT_0_1KWH    Unit = 0x01
+	UNIT_0_01KWH   Unit = 0x02
+	UNIT_0_001KWH  Unit = 0x03
+	UNIT_0_0001KWH Unit = 0x04
+	UNIT_10KWH     Unit = 0x0A
+	UNIT_100KWH    Unit = 0x0B
+	UNIT_1000KWH   Unit = 0x0C
+	UNIT_10000KWH  Unit = 0x0D
+)
+
+var units = map[Unit]float64{
+	UNIT_1KWH:      1,
+	UNIT_0_1KWH:    1e-1,
+	UNIT_0_01KWH:   1e-2,
+	UNIT_0_001KWH:  1e-3,
+	UNIT_0_0001KWH: 1e-4,
+	UNIT_10KWH:     1e+1,
+	UNIT_100KWH:    1e+2,
+	UNIT_1000KWH:   1e+3,
+	UNIT_10000KWH:  1e+4}
+
+func (u Unit) Kwh() float64 {
+	return units[u]
+}
+
+// Cumulative amount with the time it was measured (0xEA/0xEB).
+type Amount struct {
+	Time  time.Time
+	Value uint32
+}
+
+// Instantaneous currents in amperes (0xE8). T is 0 on single-phase meters.
+type Currents struct {
+	R           float64
+	T           float64
+	SinglePhase bool
+}
+
+// Instantaneous voltages in volts (0xE9). ST is 0 on single-phase meters.
+type Voltages struct {
+	RS          float64
+	ST          float64
+	SinglePhase bool
+}
+
+// Historical data of a day (0xE2/0xE4): 48 cumulative amounts at every
+// 30 minutes from 00:00, missing slots are NO_DATA. Day is as set by 0xE5.
+type History struct {
+	Day    uint16
+	Values []uint32
+}
+
+const HISTORY_SLOTS = 48
+
+// Historical data 2 (0xEC). The slots go back from Time in 30-minute steps;
+// missing slots are NO_DATA.
+type History2 struct {
+	Time    time.Time
+	Normal  []uint32
+	Reverse []uint32
+}
+
+// Time and number of slots to collect as historical data 2 (0xED).
+type History2Day struct {
+	Time  time.Time
+	Count uint8
+}
+
+const (
+	MAX_CM_AMTS = 99999999
+	NO_DATA     = 0xFFFFFFFE
+)
+
+func init() {
+	c := CLASS_SMART_EE_METER
+	register(c, EPC_0288_COMP_TRANS_RATIO, rangedUintCodec(c, EPC_0288_COMP_TRANS_RATIO, 4))
+	register(c, EPC_0288_EFFECTIVE_DIGITS, rangedUintCodec(c, EPC_0288_EFFECTIVE_DIGITS, 1))
+	register(c, EPC_0288_CM_AMTS_OF_EE_NDIR, rangedUintCodec(c, EPC_0288_CM_AMTS_OF_EE_NDIR, 4))
+	register(c, EPC_0288_UNIT_FOR_CM_AMTS_OF_EE, unitCodec())
+	register(c, EPC_0288_HDATA_OF_CM_AMTS_OF_EE_NDIR, historyCodec(EPC_0288_HDATA_OF_CM_AMTS_OF_EE_NDIR))
+	register(c, EPC_0288_CM_AMTS_OF_EE_RDIR, rangedUintCodec(c, EPC_0288_CM_AMTS_OF_EE_RDIR, 4))
+	register(c, EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR, historyCodec(EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR))
+	register(c, EPC_0288_DAY_FOR_HDATA, rangedUintCodec(c, EPC_0288_DAY_FOR_HDATA, 1))
+	register(c, EPC_0288_INST_EE, instEECodec())
+	register(c, EPC_0288_INST_CURRENTS, currentsCodec())
+	register(c, EPC_0288_INST_VOLTAGES, voltagesCodec())
+	register(c, EPC_0288_CM_AMTS_OF_EE_AT_FT_NDIR, amountCodec(EPC_0288_CM_AMTS_OF_EE_AT_FT_NDIR))
+	register(c, EPC_0288_CM_AMTS_OF_EE_AT_FT_RDIR, amountCodec(EPC_0288_CM_AMTS_OF_EE_AT_FT_RDIR))
+	register(c, EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR_2, history2Codec())
+	register(c, EPC_0288_DAY_FOR_HDATA_2, history2DayCodec())
+}
+
+func unitCodec() Codec {
+	epc := EPC_0288_UNIT_FOR_CM_AMTS_OF_EE
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 1); err != nil {
+				return nil, err
+			}
+			u := Unit(b[0])
+			if _, ok := units[u]; !ok {
+				return nil, &RangeError{Epc: epc, Value: b[0]}
+			}
+			return u, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			u, ok := i.(Unit)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if _, ok := units[u]; !ok {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			return []byte{byte(u)}, nil
+		}}
+}
+
+// Signed watts. 0x7FFFFFFF, 0x80000000 and 0x7FFFFFFE are overflow,
+// underflow and no data.
+func instEECodec() Codec {
+	epc := EPC_0288_INST_EE
+	info, ok := LookupProperty(CLASS_SMART_EE_METER, epc)
+	if !ok {
+		info = &PropertyInfo{Epc: epc, Min: -2147483647, Max: 2147483645, Ranged: true}
+	}
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 4); err != nil {
+				return nil, err
+			}
+			switch v := binary.BigEndian.Uint32(b); v {
+			case 0x7FFFFFFF:
+				return nil, ErrOverflow
+			case 0x80000000:
+				return nil, ErrUnderflow
+			case 0x7FFFFFFE:
+				return nil, ErrNoData
+			default:
+				return int32(v), nil
+			}
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			v, ok := i.(int32)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if !info.InRange(float64(v)) {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, uint32(v))
+			return b, nil
+		}}
+}
+
+// Signed 0.1 A per phase. 0x7FFF, 0x8000 and 0x7FFE are overflow, underflow
+// and no data; no data on the T phase means a single-phase meter.
+func currentsCodec() Codec {
+	epc := EPC_0288_INST_CURRENTS
+	phase := func(b []byte) (float64, error) {
+		switch v := binary.BigEndian.Uint16(b); v {
+		case 0x7FFF:
+			return 0, ErrOverflow
+		case 0x8000:
+			return 0, ErrUnderflow
+		case 0x7FFE:
+			return 0, ErrNoData
+		default:
+			return float64(int16(v)) / 10, nil
+		}
+	}
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 4); err != nil {
+				return nil, err
+			}
+			var c Currents
+			var err error
+			if c.R, err = phase(b[0:2]); err != nil {
+				return nil, err
+			}
+			if c.T, err = phase(b[2:4]); err == ErrNoData {
+				c.SinglePhase = true
+			} else if err != nil {
+				return nil, err
+			}
+			return c, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			c, ok := i.(Currents)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			b := make([]byte, 4)
+			for n, v := range []float64{c.R, c.T} {
+				r := int64(v*10 + 0.5)
+				if v < 0 {
+					r = int64(v*10 - 0.5)
+				}
+				if r < -32767 || r > 32765 {
+					return nil, &RangeError{Epc: epc, Value: v}
+				}
+				binary.BigEndian.PutUint16(b[n*2:], uint16(int16(r)))
+			}
+			if c.SinglePhase {
+				binary.BigEndian.PutUint16(b[2:], 0x7FFE)
+			}
+			return b, nil
+		}}
+}
+
+// Unsigned 0.1 V per line. 0xFFFE is no data; no data on S-T means a
+// single-phase meter.
+func voltagesCodec() Codec {
+	epc := EPC_0288_INST_VOLTAGES
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 4); err != nil {
+				return nil, err
+			}
+			rs := binary.BigEndian.Uint16(b[0:2])
+			st := binary.BigEndian.Uint16(b[2:4])
+			if rs > 0xFFFD {
+				return nil, ErrNoData
+			}
+			v := Voltages{RS: float64(rs) / 10}
+			if st > 0xFFFD {
+				v.SinglePhase = true
+			} else {
+				v.ST = float64(st) / 10
+			}
+			return v, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			v, ok := i.(Voltages)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			b := make([]byte, 4)
+			for n, x := range []float64{v.RS, v.ST} {
+				r := int64(x*10 + 0.5)
+				if r < 0 || r > 0xFFFD {
+					return nil, &RangeError{Epc: epc, Value: x}
+				}
+				binary.BigEndian.PutUint16(b[n*2:], uint16(r))
+			}
+			if v.SinglePhase {
+				binary.BigEndian.PutUint16(b[2:], 0xFFFE)
+			}
+			return b, nil
+		}}
+}
+
+func amountCodec(epc Epc) Codec {
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 11); err != nil {
+				return nil, err
+			}
+			if !validTime(b[0:7], true) {
+				return nil, &RangeError{Epc: epc, Value: b[0:7]}
+			}
+			v := binary.BigEndian.Uint32(b[7:11])
+			if v == NO_DATA {
+				return nil, ErrNoData
+			}
+			if v > MAX_CM_AMTS {
+				return nil, &RangeError{Epc: epc, Value: v}
+			}
+			return Amount{Time: decodeTime(b[0:7], true), Value: v}, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			a, ok := i.(Amount)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if a.Value > MAX_CM_AMTS {
+				return nil, &RangeError{Epc: epc, Value: a.Value}
+			}
+			b := encodeTime(a.Time, true)
+			b = append(b, 0, 0, 0, 0)
+			binary.BigEndian.PutUint32(b[7:], a.Value)
+			return b, nil
+		}}
+}
+
+func historyCodec(epc Epc) Codec {
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 2+HISTORY_SLOTS*4); err != nil {
+				return nil, err
+			}
+			h := History{
+				Day:    binary.BigEndian.Uint16(b[0:2]),
+				Values: make([]uint32, HISTORY_SLOTS)}
+			if h.Day > 99 {
+				return nil, &RangeError{Epc: epc, Value: h.Day}
+			}
+			for i := range h.Values {
+				v := binary.BigEndian.Uint32(b[2+i*4:])
+				if v > MAX_CM_AMTS && v != NO_DATA {
+					return nil, &RangeError{Epc: epc, Value: v}
+				}
+				h.Values[i] = v
+			}
+			return h, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			h, ok := i.(History)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if h.Day > 99 || len(h.Values) != HISTORY_SLOTS {
+				return nil, &RangeError{Epc: epc, Value: h.Day}
+			}
+			b := make([]byte, 2+HISTORY_SLOTS*4)
+			binary.BigEndian.PutUint16(b, h.Day)
+			for i, v := range h.Values {
+				binary.BigEndian.PutUint32(b[2+i*4:], v)
+			}
+			return b, nil
+		}}
+}
+
+func history2Codec() Codec {
+	epc := EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR_2
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if len(b) < 7 {
+				return nil, &LengthError{Epc: epc, Need: 7, Have: len(b)}
+			}
+			n := int(b[6])
+			if n < 1 || n > 12 {
+				return nil, &RangeError{Epc: epc, Value: b[6]}
+			}
+			if err := checkLen(epc, b, 7+n*8); err != nil {
+				return nil, err
+			}
+			if !validTime(b[0:6], false) {
+				return nil, &RangeError{Epc: epc, Value: b[0:6]}
+			}
+			h := History2{
+				Time:    decodeTime(b[0:6], false),
+				Normal:  make([]uint32, n),
+				Reverse: make([]uint32, n)}
+			for i := 0; i < n; i++ {
+				h.Normal[i] = binary.BigEndian.Uint32(b[7+i*8:])
+				h.Reverse[i] = binary.BigEndian.Uint32(b[11+i*8:])
+			}
+			return h, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			h, ok := i.(History2)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			n := len(h.Normal)
+			if n < 1 || n > 12 || len(h.Reverse) != n {
+				return nil, &RangeError{Epc: epc, Value: n}
+			}
+			b := append(encodeTime(h.Time, false), byte(n))
+			for i := 0; i < n; i++ {
+				b = append(b, make([]byte, 8)...)
+				binary.BigEndian.PutUint32(b[7+i*8:], h.Normal[i])
+				binary.BigEndian.PutUint32(b[11+i*8:], h.Reverse[i])
+			}
+			return b, nil
+		}}
+}
+
+func history2DayCodec() Codec {
+	epc := EPC_0288_DAY_FOR_HDATA_2
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 7); err != nil {
+				return nil, err
+			}
+			if !validTime(b[0:6], false) || b[6] < 1 || b[6] > 12 {
+				return nil, &RangeError{Epc: epc, Value: b}
+			}
+			return History2Day{Time: decodeTime(b[0:6], false), Count: b[6]}, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			d, ok := i.(History2Day)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if d.Count < 1 || d.Count > 12 || (d.Time.Minute() != 0 && d.Time.Minute() != 30) {
+				return nil, &RangeError{Epc: epc, Value: d}
+			}
+			return append(encodeTime(d.Time, false), d.Count), nil
+		}}
+}
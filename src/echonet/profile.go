@@ -0,0 +1,209 @@
+package echonet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+)
+
+// Version information of the node profile (0x82).
+type ProfileVersion struct {
+	Major     uint8
+	Minor     uint8
+	Specified bool // EHD_FORMAT1 supported
+	Arbitrary bool // EHD_FORMAT2 supported
+}
+
+type EojList []Eoj
+
+func (l EojList) Find(class Class) (Eoj, bool) {
+	for _, e := range l {
+		if e.Class == class {
+			return e, true
+		}
+	}
+	return Eoj{}, false
+}
+
+type ClassList []Class
+
+// Node profile object (0x0EF0), filled by Update from Get responses or
+// notifications.
+type NodeProfile struct {
+	Status       Status
+	Version      ProfileVersion
+	Id           IdNumber
+	Manufacturer uint32
+	UniqueId     uint16
+	Instances    uint32
+	Classes      uint16
+	InstanceList EojList
+	ClassList    ClassList
+}
+
+func init() {
+	c := CLASS_NODE_PROFILE
+	register(c, EPC_0EF0_VERSION_INFO, profileVersionCodec())
+	register(c, EPC_0EF0_UNIQUE_ID_DATA, uintCodec(EPC_0EF0_UNIQUE_ID_DATA, 2, 0, 0xFFFF))
+	register(c, EPC_0EF0_NUM_OF_SELF_NODE_INSTANCES, uint24Codec(EPC_0EF0_NUM_OF_SELF_NODE_INSTANCES))
+	register(c, EPC_0EF0_NUM_OF_SELF_NODE_CLASSES, uintCodec(EPC_0EF0_NUM_OF_SELF_NODE_CLASSES, 2, 0, 0xFFFF))
+	register(c, EPC_0EF0_INSTANCE_LIST_NOTIFICATION, eojListCodec(EPC_0EF0_INSTANCE_LIST_NOTIFICATION))
+	register(c, EPC_0EF0_SELF_NODE_INSTANCE_LIST_S, eojListCodec(EPC_0EF0_SELF_NODE_INSTANCE_LIST_S))
+	register(c, EPC_0EF0_SELF_NODE_CLASS_LIST, classListCodec())
+}
+
+// Decodes the node profile properties of the frame into np. Properties
+// without EDT (as in *_SNA) and unknown EPCs are skipped.
+func (np *NodeProfile) Update(f Frame) error {
+	for _, p := range f.Properties() {
+		if p.Pdc() == 0 {
+			continue
+		}
+		v, err := DecodeValue(CLASS_NODE_PROFILE, p)
+		if err == ErrNoCodec {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch p.Epc() {
+		case EPC_0EF0_OPERATING_STATUS:
+			np.Status = v.(Status)
+		case EPC_0EF0_VERSION_INFO:
+			np.Version = v.(ProfileVersion)
+		case EPC_0EF0_ID_NUM:
+			np.Id = v.(IdNumber)
+		case EPC_MANUFACTURER_CODE:
+			np.Manufacturer = v.(uint32)
+		case EPC_0EF0_UNIQUE_ID_DATA:
+			np.UniqueId = v.(uint16)
+		case EPC_0EF0_NUM_OF_SELF_NODE_INSTANCES:
+			np.Instances = v.(uint32)
+		case EPC_0EF0_NUM_OF_SELF_NODE_CLASSES:
+			np.Classes = v.(uint16)
+		case EPC_0EF0_INSTANCE_LIST_NOTIFICATION, EPC_0EF0_SELF_NODE_INSTANCE_LIST_S:
+			np.InstanceList = v.(EojList)
+		case EPC_0EF0_SELF_NODE_CLASS_LIST:
+			np.ClassList = v.(ClassList)
+		}
+	}
+	return nil
+}
+
+// Asks the node for its instance list (0xD6).
+func InstanceList(ctx context.Context, r Requester) (EojList, error) {
+	f, err := Get(ctx, r, Eoj{Class: CLASS_NODE_PROFILE, Instance: 1}, EPC_0EF0_SELF_NODE_INSTANCE_LIST_S)
+	if err != nil {
+		return nil, err
+	}
+	var np NodeProfile
+	if err := np.Update(f); err != nil {
+		return nil, err
+	}
+	if np.InstanceList == nil {
+		return nil, errors.New("No instance list.")
+	}
+	return np.InstanceList, nil
+}
+
+func profileVersionCodec() Codec {
+	epc := EPC_0EF0_VERSION_INFO
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 4); err != nil {
+				return nil, err
+			}
+			return ProfileVersion{
+				Major:     b[0],
+				Minor:     b[1],
+				Specified: b[2]&0x01 != 0,
+				Arbitrary: b[2]&0x02 != 0}, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			v, ok := i.(ProfileVersion)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			b := []byte{v.Major, v.Minor, 0x00, 0x00}
+			if v.Specified {
+				b[2] |= 0x01
+			}
+			if v.Arbitrary {
+				b[2] |= 0x02
+			}
+			return b, nil
+		}}
+}
+
+// Number of EOJs (at most 84) followed by the EOJs.
+func eojListCodec(epc Epc) Codec {
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if len(b) < 1 {
+				return nil, &LengthError{Epc: epc, Need: 1, Have: len(b)}
+			}
+			if b[0] > 84 {
+				return nil, &RangeError{Epc: epc, Value: b[0]}
+			}
+			if err := checkLen(epc, b, 1+int(b[0])*3); err != nil {
+				return nil, err
+			}
+			l := make(EojList, b[0])
+			for i := range l {
+				e := b[1+i*3:]
+				l[i] = Eoj{Class: Class(binary.BigEndian.Uint16(e)), Instance: e[2]}
+			}
+			return l, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			l, ok := i.(EojList)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if len(l) > 84 {
+				return nil, &RangeError{Epc: epc, Value: len(l)}
+			}
+			b := []byte{byte(len(l))}
+			for _, e := range l {
+				b = append(b, byte(e.Class>>8), byte(e.Class), e.Instance)
+			}
+			return b, nil
+		}}
+}
+
+// Number of classes (at most 8) followed by the class codes.
+func classListCodec() Codec {
+	epc := EPC_0EF0_SELF_NODE_CLASS_LIST
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if len(b) < 1 {
+				return nil, &LengthError{Epc: epc, Need: 1, Have: len(b)}
+			}
+			if b[0] > 8 {
+				return nil, &RangeError{Epc: epc, Value: b[0]}
+			}
+			if err := checkLen(epc, b, 1+int(b[0])*2); err != nil {
+				return nil, err
+			}
+			l := make(ClassList, b[0])
+			for i := range l {
+				l[i] = Class(binary.BigEndian.Uint16(b[1+i*2:]))
+			}
+			return l, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			l, ok := i.(ClassList)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if len(l) > 8 {
+				return nil, &RangeError{Epc: epc, Value: len(l)}
+			}
+			b := []byte{byte(len(l))}
+			for _, c := range l {
+				b = append(b, byte(c>>8), byte(c))
+			}
+			return b, nil
+		}}
+}
@@ -0,0 +1,120 @@
+package echonet
+
+import (
+	"fmt"
+	"sort"
+)
+
+//go:generate go run ../mragen/main.go -o registry_gen.go -skip 0x0288,0x05FF,0x0EF0 ../../mra
+
+type Rule byte
+
+const (
+	RULE_NA Rule = iota
+	RULE_OPTIONAL
+	RULE_REQUIRED
+)
+
+// Access rules of a property.
+type Access struct {
+	Get  Rule
+	Set  Rule
+	Anno Rule
+}
+
+// Property as described by the Machine Readable Appendix. Type is the MRA
+// data type (number, state, raw, ...), Format the number format (uint8,
+// int32, ...). Min and Max are valid when Ranged is set.
+type PropertyInfo struct {
+	Epc    Epc
+	Name   string
+	Title  string
+	Access Access
+	Type   string
+	Format string
+	Unit   string
+	Scale  float64
+	Min    float64
+	Max    float64
+	Ranged bool
+}
+
+type ClassInfo struct {
+	Class      Class
+	Name       string
+	Title      string
+	Properties map[Epc]*PropertyInfo
+}
+
+const CLASS_SUPER Class = 0x0000
+
+func LookupClass(class Class) (*ClassInfo, bool) {
+	c, ok := registry[class]
+	return c, ok
+}
+
+// Properties of the class take precedence over the super class ones.
+func LookupProperty(class Class, epc Epc) (*PropertyInfo, bool) {
+	if c, ok := registry[class]; ok {
+		if p, ok := c.Properties[epc]; ok {
+			return p, true
+		}
+	}
+	s, ok := registry[CLASS_SUPER]
+	if !ok || class == CLASS_NODE_PROFILE {
+		return nil, false
+	}
+	p, ok := s.Properties[epc]
+	return p, ok
+}
+
+// The MRA name of the property, or its code if unknown.
+func PropertyName(class Class, epc Epc) string {
+	if p, ok := LookupProperty(class, epc); ok {
+		return p.Name
+	}
+	return fmt.Sprintf("0x%02X", byte(epc))
+}
+
+// Property maps allowed by the access rules of the class, for objects that
+// do not tell their own.
+func ClassCapability(class Class) (*Capability, bool) {
+	c, ok := registry[class]
+	if !ok {
+		return nil, false
+	}
+	epcs := make(map[Epc]bool)
+	for epc := range c.Properties {
+		epcs[epc] = true
+	}
+	if s, ok := registry[CLASS_SUPER]; ok && class != CLASS_NODE_PROFILE {
+		for epc := range s.Properties {
+			epcs[epc] = true
+		}
+	}
+
+	caps := new(Capability)
+	for epc := range epcs {
+		p, _ := LookupProperty(class, epc)
+		if p.Access.Get != RULE_NA {
+			caps.Get = append(caps.Get, epc)
+		}
+		if p.Access.Set != RULE_NA {
+			caps.Set = append(caps.Set, epc)
+		}
+		if p.Access.Anno != RULE_NA {
+			caps.Anno = append(caps.Anno, epc)
+		}
+	}
+	for _, m := range []PropertyMap{caps.Get, caps.Set, caps.Anno} {
+		sort.Slice(m, func(i, j int) bool {
+			return m[i] < m[j]
+		})
+	}
+	return caps, true
+}
+
+// Whether v is within the range of a ranged number property.
+func (p *PropertyInfo) InRange(v float64) bool {
+	return !p.Ranged || (v >= p.Min && v <= p.Max)
+}
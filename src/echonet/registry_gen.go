@@ -0,0 +1,384 @@
+// Code generated by mragen from the ECHONET Machine Readable Appendix. DO NOT EDIT.
+
+package echonet
+
+var registry = map[Class]*ClassInfo{
+	0x0000: {
+		Class: 0x0000,
+		Name:  "superClass",
+		Title: "Device object super class",
+		Properties: map[Epc]*PropertyInfo{
+			0x80: {
+				Epc:    0x80,
+				Name:   "operationStatus",
+				Title:  "Operation status",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_OPTIONAL, Anno: RULE_REQUIRED},
+				Type:   "state",
+				Scale:  1,
+			},
+			0x81: {
+				Epc:    0x81,
+				Name:   "installationLocation",
+				Title:  "Installation location",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_REQUIRED, Anno: RULE_REQUIRED},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x82: {
+				Epc:    0x82,
+				Name:   "protocol",
+				Title:  "Standard version information",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x83: {
+				Epc:    0x83,
+				Name:   "id",
+				Title:  "Identification number",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x88: {
+				Epc:    0x88,
+				Name:   "faultStatus",
+				Title:  "Fault status",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_REQUIRED},
+				Type:   "state",
+				Scale:  1,
+			},
+			0x8A: {
+				Epc:    0x8A,
+				Name:   "manufacturer",
+				Title:  "Manufacturer code",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x8C: {
+				Epc:    0x8C,
+				Name:   "productCode",
+				Title:  "Product code",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x8D: {
+				Epc:    0x8D,
+				Name:   "serialNumber",
+				Title:  "Production number",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x8E: {
+				Epc:    0x8E,
+				Name:   "productionDate",
+				Title:  "Production date",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "date",
+				Scale:  1,
+			},
+			0x97: {
+				Epc:    0x97,
+				Name:   "currentTimeSetting",
+				Title:  "Current time setting",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_OPTIONAL, Anno: RULE_OPTIONAL},
+				Type:   "time",
+				Scale:  1,
+			},
+			0x98: {
+				Epc:    0x98,
+				Name:   "currentDateSetting",
+				Title:  "Current date setting",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_OPTIONAL, Anno: RULE_OPTIONAL},
+				Type:   "date",
+				Scale:  1,
+			},
+			0x9D: {
+				Epc:    0x9D,
+				Name:   "statusAnnouncementPropertyMap",
+				Title:  "Status change announcement property map",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x9E: {
+				Epc:    0x9E,
+				Name:   "setPropertyMap",
+				Title:  "Set property map",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x9F: {
+				Epc:    0x9F,
+				Name:   "getPropertyMap",
+				Title:  "Get property map",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+		},
+	},
+	0x0288: {
+		Class: 0x0288,
+		Name:  "lvSmartElectricEnergyMeter",
+		Title: "Low voltage smart electric energy meter",
+		Properties: map[Epc]*PropertyInfo{
+			0x80: {
+				Epc:    0x80,
+				Name:   "operationStatus",
+				Title:  "Operation status",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_REQUIRED},
+				Type:   "state",
+				Scale:  1,
+			},
+			0xD3: {
+				Epc:    0xD3,
+				Name:   "coefficient",
+				Title:  "Coefficient",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "number",
+				Format: "uint32",
+				Scale:  1,
+				Min:    1,
+				Max:    999999,
+				Ranged: true,
+			},
+			0xD7: {
+				Epc:    0xD7,
+				Name:   "numberOfEffectiveDigits",
+				Title:  "Number of effective digits for cumulative amounts of electric energy",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "number",
+				Format: "uint8",
+				Scale:  1,
+				Min:    1,
+				Max:    8,
+				Ranged: true,
+			},
+			0xE0: {
+				Epc:    0xE0,
+				Name:   "normalDirectionCumulativeElectricEnergy",
+				Title:  "Measured cumulative amount of electric energy (normal direction)",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "number",
+				Format: "uint32",
+				Scale:  1,
+				Min:    0,
+				Max:    99999999,
+				Ranged: true,
+			},
+			0xE1: {
+				Epc:    0xE1,
+				Name:   "unitForCumulativeElectricEnergy",
+				Title:  "Unit for cumulative amounts of electric energy",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "state",
+				Scale:  1,
+			},
+			0xE2: {
+				Epc:    0xE2,
+				Name:   "normalDirectionCumulativeElectricEnergyLog1",
+				Title:  "Historical data of measured cumulative amounts of electric energy 1 (normal direction)",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0xE3: {
+				Epc:    0xE3,
+				Name:   "reverseDirectionCumulativeElectricEnergy",
+				Title:  "Measured cumulative amount of electric energy (reverse direction)",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "number",
+				Format: "uint32",
+				Scale:  1,
+				Min:    0,
+				Max:    99999999,
+				Ranged: true,
+			},
+			0xE4: {
+				Epc:    0xE4,
+				Name:   "reverseDirectionCumulativeElectricEnergyLog1",
+				Title:  "Historical data of measured cumulative amounts of electric energy 1 (reverse direction)",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0xE5: {
+				Epc:    0xE5,
+				Name:   "cumulativeEnergyLogDay1",
+				Title:  "Day for which the historical data of measured cumulative amounts of electric energy is to be retrieved 1",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_REQUIRED, Anno: RULE_OPTIONAL},
+				Type:   "number",
+				Format: "uint8",
+				Scale:  1,
+				Min:    0,
+				Max:    99,
+				Ranged: true,
+			},
+			0xE7: {
+				Epc:    0xE7,
+				Name:   "instantaneousElectricPower",
+				Title:  "Measured instantaneous electric energy",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "number",
+				Format: "int32",
+				Unit:   "W",
+				Scale:  1,
+				Min:    -2147483647,
+				Max:    2147483645,
+				Ranged: true,
+			},
+			0xE8: {
+				Epc:    0xE8,
+				Name:   "instantaneousCurrent",
+				Title:  "Measured instantaneous currents",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "object",
+				Unit:   "A",
+				Scale:  0.1,
+			},
+			0xE9: {
+				Epc:    0xE9,
+				Name:   "instantaneousVoltage",
+				Title:  "Measured instantaneous voltages",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "object",
+				Unit:   "V",
+				Scale:  0.1,
+			},
+			0xEA: {
+				Epc:    0xEA,
+				Name:   "normalDirectionCumulativeElectricEnergyAtEvery30Min",
+				Title:  "Cumulative amounts of electric energy measured at fixed time (normal direction)",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_REQUIRED},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0xEB: {
+				Epc:    0xEB,
+				Name:   "reverseDirectionCumulativeElectricEnergyAtEvery30Min",
+				Title:  "Cumulative amounts of electric energy measured at fixed time (reverse direction)",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0xEC: {
+				Epc:    0xEC,
+				Name:   "cumulativeElectricEnergyLog2",
+				Title:  "Historical data of measured cumulative amounts of electric energy 2 (normal and reverse directions)",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_NA, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0xED: {
+				Epc:    0xED,
+				Name:   "cumulativeEnergyLogDay2",
+				Title:  "Day for which the historical data of measured cumulative amounts of electric energy is to be retrieved 2",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_OPTIONAL, Anno: RULE_OPTIONAL},
+				Type:   "raw",
+				Scale:  1,
+			},
+		},
+	},
+	0x05FF: {
+		Class:      0x05FF,
+		Name:       "controller",
+		Title:      "Controller",
+		Properties: map[Epc]*PropertyInfo{},
+	},
+	0x0EF0: {
+		Class: 0x0EF0,
+		Name:  "nodeProfile",
+		Title: "Node profile",
+		Properties: map[Epc]*PropertyInfo{
+			0x80: {
+				Epc:    0x80,
+				Name:   "operatingStatus",
+				Title:  "Operating status",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_OPTIONAL, Anno: RULE_REQUIRED},
+				Type:   "state",
+				Scale:  1,
+			},
+			0x82: {
+				Epc:    0x82,
+				Name:   "versionInformation",
+				Title:  "Version information",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_NA},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0x83: {
+				Epc:    0x83,
+				Name:   "id",
+				Title:  "Identification number",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_NA},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0xBF: {
+				Epc:    0xBF,
+				Name:   "uniqueIdentifierData",
+				Title:  "Unique identifier data",
+				Access: Access{Get: RULE_OPTIONAL, Set: RULE_OPTIONAL, Anno: RULE_NA},
+				Type:   "number",
+				Format: "uint16",
+				Scale:  1,
+				Min:    0,
+				Max:    65535,
+				Ranged: true,
+			},
+			0xD3: {
+				Epc:    0xD3,
+				Name:   "numberOfSelfNodeInstances",
+				Title:  "Number of self-node instances",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_NA},
+				Type:   "number",
+				Format: "uint24",
+				Scale:  1,
+				Min:    0,
+				Max:    16777215,
+				Ranged: true,
+			},
+			0xD4: {
+				Epc:    0xD4,
+				Name:   "numberOfSelfNodeClasses",
+				Title:  "Number of self-node classes",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_NA},
+				Type:   "number",
+				Format: "uint16",
+				Scale:  1,
+				Min:    0,
+				Max:    65535,
+				Ranged: true,
+			},
+			0xD5: {
+				Epc:    0xD5,
+				Name:   "instanceListNotification",
+				Title:  "Instance list notification",
+				Access: Access{Get: RULE_NA, Set: RULE_NA, Anno: RULE_REQUIRED},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0xD6: {
+				Epc:    0xD6,
+				Name:   "selfNodeInstanceListS",
+				Title:  "Self-node instance list S",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_NA},
+				Type:   "raw",
+				Scale:  1,
+			},
+			0xD7: {
+				Epc:    0xD7,
+				Name:   "selfNodeClassListS",
+				Title:  "Self-node class list S",
+				Access: Access{Get: RULE_REQUIRED, Set: RULE_NA, Anno: RULE_NA},
+				Type:   "raw",
+				Scale:  1,
+			},
+		},
+	},
+}
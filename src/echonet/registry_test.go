@@ -0,0 +1,53 @@
+package echonet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryRanges(t *testing.T) {
+	tests := []struct {
+		epc  Epc
+		edt  []byte
+		name string
+	}{
+		{EPC_0288_DAY_FOR_HDATA, []byte{100}, "cumulativeEnergyLogDay1"},
+		{EPC_0288_EFFECTIVE_DIGITS, []byte{0}, "numberOfEffectiveDigits"},
+		{EPC_0288_CM_AMTS_OF_EE_NDIR, []byte{0x05, 0xF5, 0xE1, 0x00}, "normalDirectionCumulativeElectricEnergy"},
+	}
+
+	for _, tt := range tests {
+		p := NewProperty()
+		p.SetEpc(tt.epc)
+		p.SetPdc(byte(len(tt.edt)))
+		p.SetEdt(tt.edt)
+
+		_, err := DecodeValue(CLASS_SMART_EE_METER, p)
+		e, ok := err.(*RangeError)
+		if !ok {
+			t.Errorf("%02X: err = %v, want RangeError", byte(tt.epc), err)
+			continue
+		}
+		if e.Class != CLASS_SMART_EE_METER || !strings.HasPrefix(e.Error(), tt.name) {
+			t.Errorf("%02X: err = %q", byte(tt.epc), e.Error())
+		}
+	}
+}
+
+func TestClassCapability(t *testing.T) {
+	c, ok := ClassCapability(CLASS_SMART_EE_METER)
+	if !ok {
+		t.Fatal("no class")
+	}
+	for _, epc := range []Epc{EPC_GET_MAP, EPC_0288_INST_EE, EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR_2} {
+		if !c.CanGet(epc) {
+			t.Errorf("CanGet(%02X) = false", byte(epc))
+		}
+	}
+	if !c.CanSet(EPC_0288_DAY_FOR_HDATA) || c.CanSet(EPC_0288_INST_EE) {
+		t.Errorf("Set = %X", c.Set)
+	}
+	if _, ok := ClassCapability(0xFFFF); ok {
+		t.Error("unknown class found")
+	}
+}
@@ -0,0 +1,82 @@
+package echonet
+
+import (
+	"context"
+	"errors"
+)
+
+// Sends a request to an object and returns its response. Rejected requests
+// (*_SNA) return the response together with an error.
+type Requester interface {
+	Request(ctx context.Context, deoj Eoj, esv Esv, props ...Property) (Frame, error)
+}
+
+// Property maps of an object.
+type Capability struct {
+	Get  PropertyMap
+	Set  PropertyMap
+	Anno PropertyMap
+}
+
+func (c *Capability) CanGet(epc Epc) bool {
+	return c.Get.Has(epc)
+}
+
+func (c *Capability) CanSet(epc Epc) bool {
+	return c.Set.Has(epc)
+}
+
+// Reads the Get, Set and status change property maps of the object. Only
+// the Get map is mandatory, the others are empty if the object lacks them.
+// If the Get map cannot be read, the maps allowed by the access rules of the
+// class are returned together with the error.
+func Capabilities(ctx context.Context, r Requester, eoj Eoj) (*Capability, error) {
+	c, err := readCapabilities(ctx, r, eoj)
+	if err != nil {
+		c, _ = ClassCapability(eoj.Class)
+	}
+	return c, err
+}
+
+func readCapabilities(ctx context.Context, r Requester, eoj Eoj) (*Capability, error) {
+	f, err := Get(ctx, r, eoj, EPC_GET_MAP, EPC_SET_MAP, EPC_STATUS_CHANGE_MAP)
+	if f == nil {
+		return nil, err
+	}
+
+	c := new(Capability)
+	for _, p := range f.Properties() {
+		if p.Pdc() == 0 {
+			continue
+		}
+		v, err := DecodeValue(eoj.Class, p)
+		if err != nil {
+			return nil, err
+		}
+		switch p.Epc() {
+		case EPC_GET_MAP:
+			c.Get = v.(PropertyMap)
+		case EPC_SET_MAP:
+			c.Set = v.(PropertyMap)
+		case EPC_STATUS_CHANGE_MAP:
+			c.Anno = v.(PropertyMap)
+		}
+	}
+	if c.Get == nil {
+		if err == nil {
+			err = errors.New("No Get property map.")
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get request for the properties, without EDT.
+func Get(ctx context.Context, r Requester, eoj Eoj, epcs ...Epc) (Frame, error) {
+	props := make([]Property, len(epcs))
+	for i, epc := range epcs {
+		props[i] = NewProperty()
+		props[i].SetEpc(epc)
+	}
+	return r.Request(ctx, eoj, ESV_GET, props...)
+}
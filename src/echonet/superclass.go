@@ -0,0 +1,380 @@
+package echonet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Device object super class, shared by every class.
+const (
+	EPC_OPERATION_STATUS      Epc = 0x80
+	EPC_INSTALLATION_LOCATION Epc = 0x81
+	EPC_STANDARD_VERSION      Epc = 0x82
+	EPC_ID_NUMBER             Epc = 0x83
+	EPC_FAULT_STATUS          Epc = 0x88
+	EPC_MANUFACTURER_CODE     Epc = 0x8A
+	EPC_PRODUCT_CODE          Epc = 0x8C
+	EPC_SERIAL_NUMBER         Epc = 0x8D
+	EPC_PRODUCTION_DATE       Epc = 0x8E
+	EPC_CURRENT_TIME          Epc = 0x97
+	EPC_CURRENT_DATE          Epc = 0x98
+	EPC_STATUS_CHANGE_MAP     Epc = 0x9D
+	EPC_SET_MAP               Epc = 0x9E
+	EPC_GET_MAP               Epc = 0x9F
+)
+
+type Status byte
+
+const (
+	STATUS_ON  Status = 0x30
+	STATUS_OFF Status = 0x31
+)
+
+// Installation location (0x81). Extended is set when Code is 0xFF.
+type Location struct {
+	Code     byte
+	Extended []byte
+}
+
+// Standard version information (0x82), e.g. Release 'H' revision 1.
+type Version struct {
+	Release  byte
+	Revision byte
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("Release %c rev.%d", v.Release, v.Revision)
+}
+
+// Identification number (0x83).
+type IdNumber struct {
+	Manufacturer uint32
+	Unique       []byte
+}
+
+func (i IdNumber) String() string {
+	return fmt.Sprintf("FE%06X%X", i.Manufacturer, i.Unique)
+}
+
+// Current time setting (0x97).
+type TimeOfDay struct {
+	Hour   uint8
+	Minute uint8
+}
+
+// EPCs listed in a property map (0x9D/0x9E/0x9F).
+type PropertyMap []Epc
+
+func (m PropertyMap) Has(epc Epc) bool {
+	for _, e := range m {
+		if e == epc {
+			return true
+		}
+	}
+	return false
+}
+
+var superCodecs = map[Epc]Codec{
+	EPC_OPERATION_STATUS:      statusCodec(),
+	EPC_INSTALLATION_LOCATION: locationCodec(),
+	EPC_STANDARD_VERSION:      versionCodec(),
+	EPC_ID_NUMBER:             idNumberCodec(),
+	EPC_FAULT_STATUS:          faultCodec(),
+	EPC_MANUFACTURER_CODE:     uint24Codec(EPC_MANUFACTURER_CODE),
+	EPC_PRODUCT_CODE:          asciiCodec(EPC_PRODUCT_CODE, 12),
+	EPC_SERIAL_NUMBER:         asciiCodec(EPC_SERIAL_NUMBER, 12),
+	EPC_PRODUCTION_DATE:       dateCodec(EPC_PRODUCTION_DATE),
+	EPC_CURRENT_TIME:          timeOfDayCodec(),
+	EPC_CURRENT_DATE:          dateCodec(EPC_CURRENT_DATE),
+	EPC_STATUS_CHANGE_MAP:     propertyMapCodec(EPC_STATUS_CHANGE_MAP),
+	EPC_SET_MAP:               propertyMapCodec(EPC_SET_MAP),
+	EPC_GET_MAP:               propertyMapCodec(EPC_GET_MAP)}
+
+func statusCodec() Codec {
+	epc := EPC_OPERATION_STATUS
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 1); err != nil {
+				return nil, err
+			}
+			if s := Status(b[0]); s == STATUS_ON || s == STATUS_OFF {
+				return s, nil
+			}
+			return nil, &RangeError{Epc: epc, Value: b[0]}
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			s, ok := i.(Status)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if s != STATUS_ON && s != STATUS_OFF {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			return []byte{byte(s)}, nil
+		}}
+}
+
+func locationCodec() Codec {
+	epc := EPC_INSTALLATION_LOCATION
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if len(b) == 1 && b[0] != 0xFF {
+				return Location{Code: b[0]}, nil
+			}
+			if err := checkLen(epc, b, 17); err != nil {
+				return nil, err
+			}
+			if b[0] != 0xFF {
+				return nil, &RangeError{Epc: epc, Value: b[0]}
+			}
+			return Location{Code: b[0], Extended: b[1:]}, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			l, ok := i.(Location)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if l.Code != 0xFF {
+				return []byte{l.Code}, nil
+			}
+			if len(l.Extended) != 16 {
+				return nil, &RangeError{Epc: epc, Value: l.Extended}
+			}
+			return append([]byte{0xFF}, l.Extended...), nil
+		}}
+}
+
+func versionCodec() Codec {
+	epc := EPC_STANDARD_VERSION
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 4); err != nil {
+				return nil, err
+			}
+			if b[2] < 'A' || b[2] > 'Z' {
+				return nil, &RangeError{Epc: epc, Value: b[2]}
+			}
+			return Version{Release: b[2], Revision: b[3]}, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			v, ok := i.(Version)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if v.Release < 'A' || v.Release > 'Z' {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			return []byte{0x00, 0x00, v.Release, v.Revision}, nil
+		}}
+}
+
+func idNumberCodec() Codec {
+	epc := EPC_ID_NUMBER
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if len(b) != 9 && len(b) != 17 {
+				return nil, &LengthError{Epc: epc, Need: 17, Have: len(b)}
+			}
+			if b[0] != 0xFE {
+				return nil, &RangeError{Epc: epc, Value: b[0]}
+			}
+			return IdNumber{
+				Manufacturer: uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]),
+				Unique:       b[4:]}, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			id, ok := i.(IdNumber)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if (len(id.Unique) != 5 && len(id.Unique) != 13) || id.Manufacturer > 0xFFFFFF {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			b := []byte{0xFE, byte(id.Manufacturer >> 16), byte(id.Manufacturer >> 8), byte(id.Manufacturer)}
+			return append(b, id.Unique...), nil
+		}}
+}
+
+// true when a fault has occurred.
+func faultCodec() Codec {
+	epc := EPC_FAULT_STATUS
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 1); err != nil {
+				return nil, err
+			}
+			switch b[0] {
+			case 0x41:
+				return true, nil
+			case 0x42:
+				return false, nil
+			}
+			return nil, &RangeError{Epc: epc, Value: b[0]}
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			f, ok := i.(bool)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if f {
+				return []byte{0x41}, nil
+			}
+			return []byte{0x42}, nil
+		}}
+}
+
+func uint24Codec(epc Epc) Codec {
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 3); err != nil {
+				return nil, err
+			}
+			return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2]), nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			v, ok := i.(uint32)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if v > 0xFFFFFF {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			return []byte{byte(v >> 16), byte(v >> 8), byte(v)}, nil
+		}}
+}
+
+// Fixed-length ASCII, padded with NUL or spaces.
+func asciiCodec(epc Epc, size int) Codec {
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, size); err != nil {
+				return nil, err
+			}
+			return strings.TrimRight(string(b), "\x00 "), nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			s, ok := i.(string)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if len(s) > size {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			b := make([]byte, size)
+			copy(b, s)
+			return b, nil
+		}}
+}
+
+// YYYY MM DD
+func dateCodec(epc Epc) Codec {
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 4); err != nil {
+				return nil, err
+			}
+			if b[2] < 1 || b[2] > 12 || b[3] < 1 || b[3] > 31 {
+				return nil, &RangeError{Epc: epc, Value: b}
+			}
+			return time.Date(int(binary.BigEndian.Uint16(b[0:2])), time.Month(b[2]), int(b[3]), 0, 0, 0, 0, time.Local), nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			t, ok := i.(time.Time)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			return encodeTime(t, false)[0:4], nil
+		}}
+}
+
+func timeOfDayCodec() Codec {
+	epc := EPC_CURRENT_TIME
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if err := checkLen(epc, b, 2); err != nil {
+				return nil, err
+			}
+			if b[0] > 23 || b[1] > 59 {
+				return nil, &RangeError{Epc: epc, Value: b}
+			}
+			return TimeOfDay{Hour: b[0], Minute: b[1]}, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			t, ok := i.(TimeOfDay)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			if t.Hour > 23 || t.Minute > 59 {
+				return nil, &RangeError{Epc: epc, Value: i}
+			}
+			return []byte{t.Hour, t.Minute}, nil
+		}}
+}
+
+// The number of EPCs, then the EPCs when there are fewer than 16 or else a
+// 16-byte bitmap: bit n of byte i stands for EPC 0x80 + n*0x10 + i.
+func propertyMapCodec(epc Epc) Codec {
+	return &codec{
+		decode: func(b []byte) (interface{}, error) {
+			if len(b) < 1 {
+				return nil, &LengthError{Epc: epc, Need: 1, Have: len(b)}
+			}
+			if b[0] < 16 {
+				if err := checkLen(epc, b, 1+int(b[0])); err != nil {
+					return nil, err
+				}
+				m := make(PropertyMap, b[0])
+				for i := range m {
+					m[i] = Epc(b[1+i])
+				}
+				return m, nil
+			}
+
+			if err := checkLen(epc, b, 17); err != nil {
+				return nil, err
+			}
+			m := make(PropertyMap, 0, b[0])
+			for n := uint(0); n < 8; n++ {
+				for i := 0; i < 16; i++ {
+					if b[1+i]&(1<<n) != 0 {
+						m = append(m, Epc(0x80+n*0x10+uint(i)))
+					}
+				}
+			}
+			if len(m) != int(b[0]) {
+				return nil, &RangeError{Epc: epc, Value: b[0]}
+			}
+			return m, nil
+		},
+		encode: func(i interface{}) ([]byte, error) {
+			m, ok := i.(PropertyMap)
+			if !ok {
+				return nil, &TypeError{Epc: epc, Value: i}
+			}
+			for _, e := range m {
+				if e < 0x80 {
+					return nil, &RangeError{Epc: epc, Value: e}
+				}
+			}
+			if len(m) < 16 {
+				b := []byte{byte(len(m))}
+				for _, e := range m {
+					b = append(b, byte(e))
+				}
+				return b, nil
+			}
+
+			b := make([]byte, 17)
+			for _, e := range m {
+				b[1+int(e&0x0F)] |= 1 << ((e - 0x80) >> 4)
+			}
+			for _, v := range b[1:] {
+				for ; v != 0; v &= v - 1 {
+					b[0]++
+				}
+			}
+			return b, nil
+		}}
+}
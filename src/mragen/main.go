@@ -0,0 +1,320 @@
+// Generates the echonet class/property registry from the ECHONET Machine
+// Readable Appendix (MRA) JSON data.
+//
+//	mragen [-o registry_gen.go] [-skip 0x0288,...] <mraData dir>
+//
+// The directory holds definitions/definitions.json and one JSON file per
+// class under superClass/, nodeProfile/ and devices/.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type text struct {
+	Ja string `json:"ja"`
+	En string `json:"en"`
+}
+
+type release struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type class struct {
+	Eoj        string     `json:"eoj"`
+	ClassName  text       `json:"className"`
+	ShortName  string     `json:"shortName"`
+	Properties []property `json:"elProperties"`
+}
+
+type property struct {
+	Epc          string `json:"epc"`
+	PropertyName text   `json:"propertyName"`
+	ShortName    string `json:"shortName"`
+	AccessRule   struct {
+		Get string `json:"get"`
+		Set string `json:"set"`
+		Inf string `json:"inf"`
+	} `json:"accessRule"`
+	Data         data    `json:"data"`
+	ValidRelease release `json:"validRelease"`
+}
+
+type data struct {
+	Ref        string    `json:"$ref"`
+	Type       string    `json:"type"`
+	Format     string    `json:"format"`
+	Unit       string    `json:"unit"`
+	MultipleOf *float64  `json:"multipleOf"`
+	Minimum    *float64  `json:"minimum"`
+	Maximum    *float64  `json:"maximum"`
+	OneOf      []data    `json:"oneOf"`
+	Properties []element `json:"properties"`
+	Items      *data     `json:"items"`
+}
+
+type element struct {
+	ShortName string `json:"shortName"`
+	Element   data   `json:"element"`
+}
+
+// Flattened data description, as in echonet.PropertyInfo.
+type info struct {
+	Type   string
+	Format string
+	Unit   string
+	Scale  float64
+	Min    float64
+	Max    float64
+	Ranged bool
+}
+
+var definitions map[string]data
+
+func main() {
+	out := flag.String("o", "registry_gen.go", "output file")
+	skip := flag.String("skip", "", "classes whose constants are declared by hand")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: mragen [-o file] [-skip 0x0288,...] <mraData dir>")
+		os.Exit(2)
+	}
+	dir := flag.Arg(0)
+
+	if err := loadDefinitions(filepath.Join(dir, "definitions", "definitions.json")); err != nil {
+		fail(err)
+	}
+
+	var classes []class
+	for _, sub := range []string{"superClass", "nodeProfile", "devices"} {
+		files, _ := filepath.Glob(filepath.Join(dir, sub, "*.json"))
+		for _, f := range files {
+			var c class
+			if err := readJSON(f, &c); err != nil {
+				fail(err)
+			}
+			classes = append(classes, c)
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return code(classes[i].Eoj) < code(classes[j].Eoj)
+	})
+
+	skipped := map[uint64]bool{0: true}
+	for _, s := range strings.Split(*skip, ",") {
+		if s != "" {
+			skipped[code(s)] = true
+		}
+	}
+
+	src, err := generate(classes, skipped)
+	if err != nil {
+		fail(err)
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+func loadDefinitions(path string) error {
+	var defs struct {
+		Definitions map[string]data `json:"definitions"`
+	}
+	if err := readJSON(path, &defs); err != nil {
+		return err
+	}
+	definitions = defs.Definitions
+	return nil
+}
+
+func code(s string) uint64 {
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 16)
+	if err != nil {
+		fail(fmt.Errorf("Invalid code: %s", s))
+	}
+	return v
+}
+
+func resolve(d data) data {
+	for d.Ref != "" {
+		name := strings.TrimPrefix(d.Ref, "#/definitions/")
+		r, ok := definitions[name]
+		if !ok {
+			fail(fmt.Errorf("Unknown definition: %s", d.Ref))
+		}
+		d = r
+	}
+	return d
+}
+
+// Numbers keep their range; for oneOf the first number wins (the others are
+// usually sentinel states), objects and arrays take unit and scale from
+// their first number.
+func flatten(d data) info {
+	d = resolve(d)
+
+	switch {
+	case len(d.OneOf) > 0:
+		for _, o := range d.OneOf {
+			if i := flatten(o); i.Type == "number" {
+				return i
+			}
+		}
+		return flatten(d.OneOf[0])
+	case d.Type == "object":
+		i := info{Type: "object", Scale: 1}
+		for _, e := range d.Properties {
+			if n := flatten(e.Element); n.Type == "number" {
+				i.Unit, i.Scale = n.Unit, n.Scale
+				break
+			}
+		}
+		return i
+	case d.Type == "array":
+		i := info{Type: "array", Scale: 1}
+		if d.Items != nil {
+			if n := flatten(*d.Items); n.Type == "number" {
+				i.Unit, i.Scale = n.Unit, n.Scale
+			}
+		}
+		return i
+	}
+
+	i := info{Type: d.Type, Format: d.Format, Unit: d.Unit, Scale: 1}
+	if d.MultipleOf != nil {
+		i.Scale = *d.MultipleOf
+	}
+	if d.Type == "number" && d.Minimum != nil && d.Maximum != nil {
+		i.Min, i.Max, i.Ranged = *d.Minimum, *d.Maximum, true
+	}
+	return i
+}
+
+// Release letter for ordering; "latest" sorts after every letter.
+func releaseOrder(s string) string {
+	if s == "latest" || s == "" {
+		return "~"
+	}
+	return strings.ToUpper(s)
+}
+
+// An EPC that changed between releases is listed once per release range;
+// only the newest range is kept.
+func newest(props []property) []property {
+	byEpc := make(map[uint64]property)
+	for _, p := range props {
+		e := code(p.Epc)
+		q, ok := byEpc[e]
+		if !ok || releaseOrder(p.ValidRelease.To) > releaseOrder(q.ValidRelease.To) ||
+			(p.ValidRelease.To == q.ValidRelease.To && releaseOrder(p.ValidRelease.From) > releaseOrder(q.ValidRelease.From)) {
+			byEpc[e] = p
+		}
+	}
+
+	out := make([]property, 0, len(byEpc))
+	for _, p := range byEpc {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return code(out[i].Epc) < code(out[j].Epc)
+	})
+	return out
+}
+
+func rule(s string) string {
+	switch s {
+	case "required", "required_c":
+		return "RULE_REQUIRED"
+	case "optional":
+		return "RULE_OPTIONAL"
+	}
+	return "RULE_NA"
+}
+
+// lvSmartElectricEnergyMeter -> LV_SMART_ELECTRIC_ENERGY_METER
+func constName(s string) string {
+	var b bytes.Buffer
+	for i, r := range s {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func generate(classes []class, skipped map[uint64]bool) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// Code generated by mragen from the ECHONET Machine Readable Appendix. DO NOT EDIT.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package echonet")
+	fmt.Fprintln(&b)
+
+	var consts bytes.Buffer
+	for _, c := range classes {
+		if !skipped[code(c.Eoj)] {
+			fmt.Fprintf(&consts, "CLASS_%s Class = 0x%04X\n", constName(c.ShortName), code(c.Eoj))
+		}
+	}
+	if consts.Len() > 0 {
+		fmt.Fprintf(&b, "const (\n%s)\n\n", consts.String())
+	}
+
+	fmt.Fprintln(&b, "var registry = map[Class]*ClassInfo{")
+	for _, c := range classes {
+		fmt.Fprintf(&b, "0x%04X: {\nClass: 0x%04X,\nName: %q,\nTitle: %q,\nProperties: map[Epc]*PropertyInfo{\n",
+			code(c.Eoj), code(c.Eoj), c.ShortName, c.ClassName.En)
+
+		for _, p := range newest(c.Properties) {
+			i := flatten(p.Data)
+			fmt.Fprintf(&b, "0x%02X: {\nEpc: 0x%02X,\nName: %q,\nTitle: %q,\n", code(p.Epc), code(p.Epc), p.ShortName, p.PropertyName.En)
+			fmt.Fprintf(&b, "Access: Access{Get: %s, Set: %s, Anno: %s},\n",
+				rule(p.AccessRule.Get), rule(p.AccessRule.Set), rule(p.AccessRule.Inf))
+			fmt.Fprintf(&b, "Type: %q,\n", i.Type)
+			if i.Format != "" {
+				fmt.Fprintf(&b, "Format: %q,\n", i.Format)
+			}
+			if i.Unit != "" {
+				fmt.Fprintf(&b, "Unit: %q,\n", i.Unit)
+			}
+			fmt.Fprintf(&b, "Scale: %s,\n", strconv.FormatFloat(i.Scale, 'f', -1, 64))
+			if i.Ranged {
+				fmt.Fprintf(&b, "Min: %s,\nMax: %s,\nRanged: true,\n",
+					strconv.FormatFloat(i.Min, 'f', -1, 64), strconv.FormatFloat(i.Max, 'f', -1, 64))
+			}
+			fmt.Fprintln(&b, "},")
+		}
+		fmt.Fprintln(&b, "},\n},")
+	}
+	fmt.Fprintln(&b, "}")
+
+	return format.Source(b.Bytes())
+}
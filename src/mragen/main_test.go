@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testClasses = `[
+{"eoj": "0x0000", "shortName": "superClass", "className": {"en": "Super class"},
+ "elProperties": [
+  {"epc": "0x80", "shortName": "operationStatus", "propertyName": {"en": "Operation status"},
+   "accessRule": {"get": "required", "set": "optional", "inf": "required"},
+   "data": {"$ref": "#/definitions/state_ON-OFF_3031"}}]},
+{"eoj": "0x0130", "shortName": "homeAirConditioner", "className": {"en": "Home air conditioner"},
+ "elProperties": [
+  {"epc": "0xB3", "shortName": "targetTemperature", "propertyName": {"en": "Set temperature value"},
+   "accessRule": {"get": "required", "set": "required", "inf": "optional"},
+   "validRelease": {"from": "A", "to": "J"},
+   "data": {"type": "number", "format": "uint8", "unit": "Celsius", "minimum": 0, "maximum": 50}},
+  {"epc": "0xB3", "shortName": "targetTemperature", "propertyName": {"en": "Set temperature value"},
+   "accessRule": {"get": "required", "set": "required", "inf": "optional"},
+   "validRelease": {"from": "K", "to": "latest"},
+   "data": {"type": "number", "format": "uint8", "unit": "Celsius", "minimum": 0, "maximum": 60}},
+  {"epc": "0x80", "shortName": "operationStatus", "propertyName": {"en": "Operation status"},
+   "accessRule": {"get": "required", "set": "required", "inf": "required"},
+   "data": {"$ref": "#/definitions/state_ON-OFF_3031"}}]},
+{"eoj": "0x0288", "shortName": "lvSmartElectricEnergyMeter", "className": {"en": "Low voltage smart electric energy meter"},
+ "elProperties": []}
+]`
+
+func TestGenerate(t *testing.T) {
+	definitions = map[string]data{"state_ON-OFF_3031": {Type: "state"}}
+
+	var classes []class
+	if err := json.Unmarshal([]byte(testClasses), &classes); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(classes, map[uint64]bool{0: true, 0x0288: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+
+	for _, want := range []string{
+		"CLASS_HOME_AIR_CONDITIONER Class = 0x0130",
+		"Max:    60,",
+		"Access: Access{Get: RULE_REQUIRED, Set: RULE_REQUIRED, Anno: RULE_OPTIONAL},",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"CLASS_SUPER_CLASS", "CLASS_LV_SMART_ELECTRIC_ENERGY_METER", "Max:    50,"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("unexpected %q in\n%s", unwanted, out)
+		}
+	}
+	if n := strings.Count(out, "0xB3: {"); n != 1 {
+		t.Errorf("0xB3 generated %d times", n)
+	}
+}
+
+func TestConstName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"lvSmartElectricEnergyMeter", "LV_SMART_ELECTRIC_ENERGY_METER"},
+		{"controller", "CONTROLLER"},
+	}
+	for _, tt := range tests {
+		if got := constName(tt.in); got != tt.want {
+			t.Errorf("constName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
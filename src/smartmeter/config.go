@@ -1,13 +1,23 @@
 package main
 
 import (
+	bp "bp35a1"
+	"fmt"
 	"github.com/BurntSushi/toml"
+	"time"
 )
 
 type config struct {
-	RouteB   routeB `toml:"routeb"`
+	RouteB   routeB  `toml:"routeb"`
+	Meters   []meter `toml:"meter"`
+	Serial   serialConf
 	Database database
 	Log      logger `toml:"logger"`
+	Link     link
+	Module   module
+	Sleep    sleep
+	Join     joinPolicy
+	History  history
 }
 
 type routeB struct {
@@ -15,11 +25,57 @@ type routeB struct {
 	Pwd string `toml:"password"`
 }
 
+type meter struct {
+	Name   string
+	Id     string
+	Pwd    string `toml:"password"`
+	Serial string
+	Device string
+	State  string
+}
+
+type serialConf struct {
+	Device      string
+	Vendor      string
+	Product     string
+	Serial      string
+	Glob        string
+	Baud        int
+	ReadTimeout int `toml:"read_timeout"` // milliseconds
+}
+
 type database struct {
 	Host string
 	Port int
 }
 
+type link struct {
+	Interval int // seconds
+	Window   int
+	MaxLoss  float64 `toml:"max_loss"`
+	MaxRTT   int     `toml:"max_rtt"` // milliseconds
+}
+
+type module struct {
+	State string
+}
+
+type sleep struct {
+	Enabled bool
+	Idle    int // seconds
+}
+
+type joinPolicy struct {
+	Retries    int
+	Backoff    int `toml:"backoff"`     // seconds
+	MaxBackoff int `toml:"max_backoff"` // seconds
+	Rescan     int // failed joins before re-scanning
+}
+
+type history struct {
+	Days int // days of 30-minute data to write on start
+}
+
 type logger struct {
 	Level string
 }
@@ -28,3 +84,83 @@ func loadConfig(path string, conf *config) error {
 	_, err := toml.DecodeFile(path, conf)
 	return err
 }
+
+func (c *config) meters() []meter {
+	if len(c.Meters) == 0 {
+		return []meter{{
+			Name:  "meter1",
+			Id:    c.RouteB.Id,
+			Pwd:   c.RouteB.Pwd,
+			State: c.Module.State}}
+	}
+
+	meters := make([]meter, len(c.Meters))
+	for i, m := range c.Meters {
+		if m.Name == "" {
+			m.Name = defaultName(&m, i)
+		}
+		if m.State == "" && c.Module.State != "" {
+			m.State = fmt.Sprintf("%s.%s", c.Module.State, m.label())
+		}
+		meters[i] = m
+	}
+	return meters
+}
+
+// Route B credentials are masked in everything that is logged.
+func (c *config) addSecrets() {
+	bp.AddSecret(c.RouteB.Id)
+	bp.AddSecret(c.RouteB.Pwd)
+	for _, m := range c.Meters {
+		bp.AddSecret(m.Id)
+		bp.AddSecret(m.Pwd)
+	}
+}
+
+func (c *serialConf) controllerConfig(tty string) *bp.SerialConfig {
+	return &bp.SerialConfig{
+		Name:        tty,
+		Baud:        c.Baud,
+		ReadTimeout: time.Duration(c.ReadTimeout) * time.Millisecond}
+}
+
+func (m *meter) label() string {
+	return m.Name
+}
+
+// The dongle serial or the position in the config; never derived from the
+// Route B ID, which is a secret.
+func defaultName(m *meter, i int) string {
+	if m.Serial != "" {
+		return m.Serial
+	}
+	return fmt.Sprintf("meter%d", i+1)
+}
+
+func (j *joinPolicy) retries() int {
+	if j.Retries <= 0 {
+		return 5
+	}
+	return j.Retries
+}
+
+func (j *joinPolicy) backoff() time.Duration {
+	if j.Backoff <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(j.Backoff) * time.Second
+}
+
+func (j *joinPolicy) maxBackoff() time.Duration {
+	if j.MaxBackoff <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(j.MaxBackoff) * time.Second
+}
+
+func (j *joinPolicy) rescan() int {
+	if j.Rescan <= 0 {
+		return 3
+	}
+	return j.Rescan
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/cihub/seelog"
+)
+
+const defaultGlob = "/dev/serial/by-id/*"
+
+type dongle struct {
+	Tty    string
+	Serial string
+}
+
+func getDongles(conf *serialConf) ([]dongle, error) {
+	if conf.Device != "" {
+		return []dongle{{Tty: conf.Device, Serial: conf.Serial}}, nil
+	}
+
+	dongles, err := udevDongles(conf)
+	if err == nil {
+		return dongles, nil
+	}
+	log.Infof("%s Falling back to %s", err, conf.glob())
+
+	return globDongles(conf)
+}
+
+func globDongles(conf *serialConf) ([]dongle, error) {
+	paths, err := filepath.Glob(conf.glob())
+	if err != nil {
+		return nil, err
+	}
+
+	dongles := []dongle{}
+	for _, p := range paths {
+		serial := byIdSerial(filepath.Base(p))
+		if conf.Serial != "" && serial != conf.Serial {
+			continue
+		}
+		if tty, err := filepath.EvalSymlinks(p); err == nil {
+			p = tty
+		}
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		dongles = append(dongles, dongle{Tty: p, Serial: serial})
+	}
+
+	if len(dongles) <= 0 {
+		return nil, errors.New("No devices found.")
+	}
+	return dongles, nil
+}
+
+// usb-FTDI_FT232R_USB_UART_A1B2C3D4-if00-port0 -> A1B2C3D4
+func byIdSerial(name string) string {
+	if i := strings.LastIndex(name, "-if"); i > 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, "_"); i >= 0 {
+		return name[i+1:]
+	}
+	return ""
+}
+
+func (c *serialConf) glob() string {
+	if c.Glob != "" {
+		return c.Glob
+	}
+	return defaultGlob
+}
+
+// Whether every meter names its device, so no discovery is needed.
+func explicitDevices(meters []meter) bool {
+	for _, m := range meters {
+		if m.Device == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func assignDongles(meters []meter, dongles []dongle) map[int]dongle {
+	assigned := make(map[int]dongle)
+	used := make(map[int]bool)
+
+	for i, m := range meters {
+		if m.Device != "" {
+			assigned[i] = dongle{Tty: m.Device, Serial: m.Serial}
+			for j, d := range dongles {
+				if d.Tty == m.Device {
+					used[j] = true
+				}
+			}
+		}
+	}
+
+	for i, m := range meters {
+		if _, ok := assigned[i]; ok || m.Serial == "" {
+			continue
+		}
+		for j, d := range dongles {
+			if !used[j] && d.Serial == m.Serial {
+				assigned[i] = d
+				used[j] = true
+				break
+			}
+		}
+	}
+
+	for i, m := range meters {
+		if _, ok := assigned[i]; ok || m.Serial != "" || m.Device != "" {
+			continue
+		}
+		for j, d := range dongles {
+			if !used[j] {
+				assigned[i] = d
+				used[j] = true
+				break
+			}
+		}
+	}
+	return assigned
+}
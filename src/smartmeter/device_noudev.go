@@ -0,0 +1,12 @@
+//go:build noudev
+// +build noudev
+
+package main
+
+import (
+	"errors"
+)
+
+func udevDongles(conf *serialConf) ([]dongle, error) {
+	return nil, errors.New("udev is not available.")
+}
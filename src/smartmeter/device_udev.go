@@ -0,0 +1,75 @@
+//go:build !noudev
+// +build !noudev
+
+package main
+
+import (
+	"errors"
+	"github.com/jochenvg/go-udev"
+)
+
+func udevDongles(conf *serialConf) ([]dongle, error) {
+	u := udev.Udev{}
+
+	usbs, err := findDevices(&u, func(enum *udev.Enumerate) {
+		enum.AddMatchSubsystem("usb")
+		if conf.Vendor != "" || conf.Product != "" {
+			if conf.Vendor != "" {
+				enum.AddMatchSysattr("idVendor", conf.Vendor)
+			}
+			if conf.Product != "" {
+				enum.AddMatchSysattr("idProduct", conf.Product)
+			}
+		} else {
+			enum.AddMatchSysattr("interface", "FT232R USB UART")
+		}
+		enum.AddMatchIsInitialized()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dongles := []dongle{}
+	for _, usb := range usbs {
+		tty, err := findDevice(&u, func(enum *udev.Enumerate) {
+			enum.AddMatchSubsystem("tty")
+			enum.AddMatchParent(usb)
+		})
+		if err != nil {
+			continue
+		}
+
+		serial := usb.SysattrValue("serial")
+		if p := usb.Parent(); serial == "" && p != nil {
+			serial = p.SysattrValue("serial")
+		}
+		if conf.Serial != "" && serial != conf.Serial {
+			continue
+		}
+		dongles = append(dongles, dongle{Tty: tty.Devnode(), Serial: serial})
+	}
+
+	if len(dongles) <= 0 {
+		return nil, errors.New("No devices found.")
+	}
+	return dongles, nil
+}
+
+func findDevices(u *udev.Udev, filter func(*udev.Enumerate)) ([]*udev.Device, error) {
+	enum := u.NewEnumerate()
+	filter(enum)
+	devices, err := enum.Devices()
+
+	if len(devices) <= 0 {
+		return nil, errors.New("No devices found.")
+	}
+	return devices, err
+}
+
+func findDevice(u *udev.Udev, filter func(*udev.Enumerate)) (*udev.Device, error) {
+	devices, err := findDevices(u, filter)
+	if err != nil {
+		return nil, err
+	}
+	return devices[0], nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"echonet"
+	"errors"
+	"fmt"
+	"github.com/influxdata/influxdb/client/v2"
+	"sort"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+// Cumulative energy in kWh as measured at Time.
+type halfHour struct {
+	Time      time.Time
+	Import    float64
+	Export    float64
+	HasImport bool
+	HasExport bool
+}
+
+// kWh per count of the cumulative amounts: the coefficient (0xD3, 1 when
+// the meter lacks it) times the unit (0xE1).
+func (n *node) scale(ctx context.Context, index uint8) (float64, error) {
+	f, err := n.get(ctx, echonet.CLASS_SMART_EE_METER, index,
+		echonet.EPC_0288_COMP_TRANS_RATIO, echonet.EPC_0288_UNIT_FOR_CM_AMTS_OF_EE)
+	if f == nil {
+		return 0, err
+	}
+
+	p, ok := property(f, echonet.EPC_0288_UNIT_FOR_CM_AMTS_OF_EE)
+	if !ok || p.Pdc() == 0 {
+		if err == nil {
+			err = errors.New("No unit for cumulative amounts.")
+		}
+		return 0, err
+	}
+	v, err := echonet.DecodeValue(echonet.CLASS_SMART_EE_METER, p)
+	if err != nil {
+		return 0, err
+	}
+	scale := v.(echonet.Unit).Kwh()
+
+	if p, ok := property(f, echonet.EPC_0288_COMP_TRANS_RATIO); ok && p.Pdc() > 0 {
+		v, err := echonet.DecodeValue(echonet.CLASS_SMART_EE_METER, p)
+		if err != nil {
+			return 0, err
+		}
+		scale *= float64(v.(uint32))
+	}
+	return scale, nil
+}
+
+// The 48 slots of the day that is the given number of days back (0-99).
+func (n *node) history(ctx context.Context, index uint8, day uint8, scale float64) ([]halfHour, error) {
+	c := echonet.CLASS_SMART_EE_METER
+
+	p, err := echonet.EncodeValue(c, echonet.EPC_0288_DAY_FOR_HDATA, day)
+	if err != nil {
+		return nil, err
+	}
+
+	n.hmutex.Lock()
+	if _, err := n.Request(ctx, echonet.Eoj{Class: c, Instance: index}, echonet.ESV_SETC, p); err != nil {
+		n.hmutex.Unlock()
+		return nil, err
+	}
+	f, err := n.get(ctx, c, index,
+		echonet.EPC_0288_HDATA_OF_CM_AMTS_OF_EE_NDIR, echonet.EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR)
+	n.hmutex.Unlock()
+	if f == nil {
+		return nil, err
+	}
+
+	var hist [2]*echonet.History
+	for i, epc := range []echonet.Epc{echonet.EPC_0288_HDATA_OF_CM_AMTS_OF_EE_NDIR, echonet.EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR} {
+		p, ok := property(f, epc)
+		if !ok || p.Pdc() == 0 {
+			continue
+		}
+		v, err := echonet.DecodeValue(c, p)
+		if err != nil {
+			return nil, err
+		}
+		h := v.(echonet.History)
+		if h.Day != uint16(day) {
+			return nil, fmt.Errorf("Historical data is for day %d, not %d.", h.Day, day)
+		}
+		hist[i] = &h
+	}
+	if hist[0] == nil && hist[1] == nil {
+		if err == nil {
+			err = fmt.Errorf("No historical data for day %d.", day)
+		}
+		return nil, err
+	}
+
+	y, mo, d := time.Now().AddDate(0, 0, -int(day)).Date()
+	base := time.Date(y, mo, d, 0, 0, 0, 0, time.Local)
+
+	slots := make([]halfHour, echonet.HISTORY_SLOTS)
+	for i := range slots {
+		s := &slots[i]
+		s.Time = base.Add(time.Duration(i) * 30 * time.Minute)
+		if hist[0] != nil && hist[0].Values[i] != echonet.NO_DATA {
+			s.Import, s.HasImport = float64(hist[0].Values[i])*scale, true
+		}
+		if hist[1] != nil && hist[1].Values[i] != echonet.NO_DATA {
+			s.Export, s.HasExport = float64(hist[1].Values[i])*scale, true
+		}
+	}
+	return slots, nil
+}
+
+// The slots from from to to, read through 0xED/0xEC in chunks of 12. Each
+// chunk goes back from the time set in 0xED.
+func (n *node) historyRange(ctx context.Context, index uint8, from, to time.Time, scale float64) ([]halfHour, error) {
+	c := echonet.CLASS_SMART_EE_METER
+	const slot = 30 * time.Minute
+
+	from = from.Add(slot - 1).Truncate(slot)
+	to = to.Truncate(slot)
+
+	var slots []halfHour
+	for t := to; !t.Before(from); {
+		count := int(t.Sub(from)/slot) + 1
+		if count > 12 {
+			count = 12
+		}
+
+		p, err := echonet.EncodeValue(c, echonet.EPC_0288_DAY_FOR_HDATA_2, echonet.History2Day{Time: t, Count: uint8(count)})
+		if err != nil {
+			return nil, err
+		}
+
+		n.hmutex.Lock()
+		if _, err := n.Request(ctx, echonet.Eoj{Class: c, Instance: index}, echonet.ESV_SETC, p); err != nil {
+			n.hmutex.Unlock()
+			return nil, err
+		}
+		f, err := n.get(ctx, c, index, echonet.EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR_2)
+		n.hmutex.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		p, ok := property(f, echonet.EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR_2)
+		if !ok {
+			return nil, errors.New("No historical data 2.")
+		}
+		v, err := echonet.DecodeValue(c, p)
+		if err != nil {
+			return nil, err
+		}
+		h := v.(echonet.History2)
+
+		for i := range h.Normal {
+			s := halfHour{Time: h.Time.Add(-time.Duration(i) * slot)}
+			if h.Normal[i] != echonet.NO_DATA {
+				s.Import, s.HasImport = float64(h.Normal[i])*scale, true
+			}
+			if h.Reverse[i] != echonet.NO_DATA {
+				s.Export, s.HasExport = float64(h.Reverse[i])*scale, true
+			}
+			slots = append(slots, s)
+		}
+		t = t.Add(-time.Duration(count) * slot)
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Time.Before(slots[j].Time)
+	})
+	return slots, nil
+}
+
+// Writes the historical data of the last days, oldest first.
+func backfill(n *node, index uint8, m *meter, days int, cli client.Client) {
+	if days > 99 {
+		days = 99
+	}
+	ctx := context.Background()
+
+	scale, err := n.scale(ctx, index)
+	if err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+
+	for day := days; day >= 0; day-- {
+		slots, err := n.history(ctx, index, uint8(day), scale)
+		if err != nil {
+			log.Errorf("[%s] %s", m.label(), err)
+			continue
+		}
+		writeHistory(cli, m, slots)
+		log.Infof("[%s] Wrote historical data of %d days ago.", m.label(), day)
+	}
+}
+
+// Fills the slots between from and now, e.g. after the link was down.
+func fillGap(n *node, index uint8, m *meter, from time.Time, cli client.Client) {
+	ctx := context.Background()
+
+	scale, err := n.scale(ctx, index)
+	if err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+	slots, err := n.historyRange(ctx, index, from, time.Now(), scale)
+	if err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+	writeHistory(cli, m, slots)
+	log.Infof("[%s] Filled %d slots since %s.", m.label(), len(slots), from.Format(time.RFC3339))
+}
+
+func writeHistory(cli client.Client, m *meter, slots []halfHour) {
+	samples := make([]sample, 0, len(slots))
+	for _, s := range slots {
+		fields := make(map[string]interface{})
+		if s.HasImport {
+			fields["import"] = s.Import
+		}
+		if s.HasExport {
+			fields["export"] = s.Export
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		samples = append(samples, sample{fields: fields, time: s.Time})
+	}
+	writePoints(cli, m, "History", samples)
+}
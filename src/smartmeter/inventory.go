@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"echonet"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+// Logs what the meter reports about itself and warns when its clock is off
+// by more than maxSkew.
+func inventory(n *node, index uint8, m *meter, maxSkew time.Duration) {
+	c := echonet.CLASS_SMART_EE_METER
+	f, err := n.get(context.Background(), c, index,
+		echonet.EPC_MANUFACTURER_CODE, echonet.EPC_SERIAL_NUMBER, echonet.EPC_STANDARD_VERSION,
+		echonet.EPC_CURRENT_DATE, echonet.EPC_CURRENT_TIME)
+	if f == nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+	now := time.Now()
+
+	values := make(map[echonet.Epc]interface{})
+	for _, p := range f.Properties() {
+		if p.Pdc() == 0 {
+			continue
+		}
+		v, err := echonet.DecodeValue(c, p)
+		if err != nil {
+			log.Warnf("[%s] %s", m.label(), err)
+			continue
+		}
+		values[p.Epc()] = v
+	}
+
+	if v, ok := values[echonet.EPC_MANUFACTURER_CODE]; ok {
+		log.Infof("[%s] Manufacturer code: %06X", m.label(), v)
+	}
+	if v, ok := values[echonet.EPC_SERIAL_NUMBER]; ok {
+		log.Infof("[%s] Serial number: %s", m.label(), v)
+	}
+	if v, ok := values[echonet.EPC_STANDARD_VERSION]; ok {
+		log.Infof("[%s] Standard version: %s", m.label(), v)
+	}
+
+	d, ok := values[echonet.EPC_CURRENT_DATE].(time.Time)
+	t, ok2 := values[echonet.EPC_CURRENT_TIME].(echonet.TimeOfDay)
+	if !ok || !ok2 {
+		return
+	}
+	clock := d.Add(time.Duration(t.Hour)*time.Hour + time.Duration(t.Minute)*time.Minute)
+	if skew := clock.Sub(now.Truncate(time.Minute)); skew > maxSkew || skew < -maxSkew {
+		log.Warnf("[%s] Meter clock is off by %v.", m.label(), skew)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	bp "bp35a1"
+	"context"
+	"echonet"
+	"fmt"
+	"github.com/influxdata/influxdb/client/v2"
+	"math/rand"
+	"net"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+type joinAttempt struct {
+	Time    time.Time
+	PanId   uint16
+	Channel uint8
+	Addr    string
+	LQI     uint8
+	Err     error
+}
+
+func (a *joinAttempt) String() string {
+	s := "ok"
+	if a.Err != nil {
+		s = a.Err.Error()
+	}
+	return fmt.Sprintf("%s PAN %04X ch %02X addr %s lqi %d: %s",
+		a.Time.Format(time.RFC3339), a.PanId, a.Channel, a.Addr, a.LQI, s)
+}
+
+type joiner struct {
+	ctrl     bp.Controller
+	mod      bp.Module
+	m        *meter
+	policy   *joinPolicy
+	cli      client.Client
+	rand     *rand.Rand
+	attempts []joinAttempt
+}
+
+func newJoiner(ctrl bp.Controller, mod bp.Module, m *meter, policy *joinPolicy, cli client.Client) *joiner {
+	return &joiner{
+		ctrl:   ctrl,
+		mod:    mod,
+		m:      m,
+		policy: policy,
+		cli:    cli,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Joins one of the candidates, best first. On failure it fails over to the
+// next candidate and re-scans after policy.rescan() consecutive failures.
+// Returns the joined PAN and the expectation for the first INF from the meter.
+func (j *joiner) join(candidates []*panState) (*panState, bp.Operation, error) {
+	var current *panState
+	failures := 0
+	for i := 0; i <= j.policy.retries(); i++ {
+		if i > 0 {
+			d := j.backoff(i)
+			log.Infof("[%s] Retrying join in %v.", j.m.label(), d)
+			time.Sleep(d)
+		}
+
+		if failures > 0 && failures%j.policy.rescan() == 0 {
+			log.Infof("[%s] Re-scanning after %d failed joins.", j.m.label(), failures)
+			pans, err := scanPan(j.ctrl, j.m)
+			if err != nil {
+				log.Errorf("[%s] %s", j.m.label(), err)
+			} else {
+				candidates = pans
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, nil, fmt.Errorf("No PAN to join for %s.", j.m.label())
+		}
+
+		pan := candidates[0]
+		if pan != current {
+			if err := usePan(j.mod, pan); err != nil {
+				return nil, nil, err
+			}
+			current = pan
+		}
+
+		inf, err := j.attempt(pan)
+		if err == nil {
+			return pan, inf, nil
+		}
+		failures++
+
+		// Fail over to the next-best PAN; the failed one goes to the back.
+		candidates = append(candidates[1:], pan)
+	}
+
+	for _, a := range j.attempts {
+		log.Errorf("[%s] Join attempt %s", j.m.label(), a.String())
+	}
+	return nil, nil, fmt.Errorf("Join failed after %d attempts.", len(j.attempts))
+}
+
+func (j *joiner) attempt(pan *panState) (bp.Operation, error) {
+	a := joinAttempt{
+		Time:    time.Now(),
+		PanId:   pan.PanId,
+		Channel: pan.Channel,
+		Addr:    pan.Addr,
+		LQI:     pan.LQI}
+
+	inf := j.ctrl.Expect(
+		func(e bp.Event) bool {
+			if e.Type() == bp.ERXUDP && e.(bp.EventRxUDP).LPort() == bp.PORT_ECHONET {
+				f, err := echonet.Decode(e.(bp.EventRxUDP).Data())
+				return err == nil && f.Esv() == echonet.ESV_INF
+			}
+			return false
+		}, time.Minute)
+
+	cmd, err := bp.Join(net.ParseIP(pan.IpAddr))
+	if err == nil {
+		err = j.ctrl.Begin(cmd).Wait(context.Background())
+	}
+	a.Err = err
+	j.record(a)
+
+	if err != nil {
+		inf.Cancel()
+		log.Warnf("[%s] Join attempt %d failed: %s", j.m.label(), len(j.attempts), err)
+		return nil, err
+	}
+	log.Infof("[%s] Joined PAN %04X after %d attempts.", j.m.label(), pan.PanId, len(j.attempts))
+	return inf, nil
+}
+
+func (j *joiner) record(a joinAttempt) {
+	j.attempts = append(j.attempts, a)
+
+	if j.cli == nil {
+		return
+	}
+	fields := map[string]interface{}{
+		"attempt": len(j.attempts),
+		"pan_id":  int(a.PanId),
+		"channel": int(a.Channel),
+		"lqi":     int(a.LQI),
+		"ok":      a.Err == nil}
+	if a.Err != nil {
+		fields["error"] = a.Err.Error()
+	}
+	writePoint(j.cli, j.m, "Join", fields, a.Time)
+}
+
+// Exponential backoff, jittered over the upper half of the interval.
+func (j *joiner) backoff(n int) time.Duration {
+	d := j.policy.backoff()
+	for i := 1; i < n && d < j.policy.maxBackoff(); i++ {
+		d *= 2
+	}
+	if d > j.policy.maxBackoff() {
+		d = j.policy.maxBackoff()
+	}
+	return d/2 + time.Duration(j.rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	bp "bp35a1"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/cihub/seelog"
+)
+
+type panState struct {
+	PairId  string `toml:"pair_id"`
+	Channel uint8
+	PanId   uint16 `toml:"pan_id"`
+	Addr    string
+	IpAddr  string `toml:"ipaddr"`
+	LQI     uint8  `toml:"lqi"`
+}
+
+// Scans until at least one coordinator with the meter's pairing ID answers.
+// The PANs are returned best LQI first.
+func scanPan(ctrl bp.Controller, m *meter) ([]*panState, error) {
+	scan, err := bp.Scan(2, 0xffffffff, 6)
+	if err != nil {
+		return nil, err
+	}
+
+	var descs []bp.EventPanDesc
+	for len(descs) == 0 {
+		op := ctrl.Begin(scan)
+		if err := op.Wait(context.Background()); err != nil {
+			return nil, err
+		}
+		for _, e := range op.Received() {
+			if e.Type() == bp.EPANDESC {
+				d := e.(bp.EventPanDesc)
+				if strings.HasSuffix(strings.ToUpper(m.Id), strings.ToUpper(d.PairId())) {
+					descs = append(descs, d)
+				}
+			}
+		}
+	}
+	sort.SliceStable(descs, func(i, j int) bool {
+		return descs[i].LQI() > descs[j].LQI()
+	})
+
+	pans := make([]*panState, 0, len(descs))
+	for _, d := range descs {
+		ll64, err := bp.LL64(d.Addr())
+		if err != nil {
+			return nil, err
+		}
+		k, ok := ctrl.Send(ll64).(bp.Result)
+		if !ok {
+			return nil, errors.New("No link-local address for the meter.")
+		}
+
+		pans = append(pans, &panState{
+			PairId:  d.PairId(),
+			Channel: d.Channel(),
+			PanId:   d.PanId(),
+			Addr:    d.Addr(),
+			IpAddr:  net.ParseIP(k.Result()).String(),
+			LQI:     d.LQI()})
+	}
+	return pans, nil
+}
+
+func usePan(mod bp.Module, pan *panState) error {
+	if err := mod.SetRegister(bp.REG_CHANNEL, fmt.Sprintf("%02X", pan.Channel)); err != nil {
+		return err
+	}
+	return mod.SetRegister(bp.REG_PAN_ID, fmt.Sprintf("%04X", pan.PanId))
+}
+
+func restorePan(mod bp.Module, m *meter) (*panState, bool) {
+	if m.State == "" {
+		return nil, false
+	}
+
+	var pan panState
+	if _, err := toml.DecodeFile(m.State, &pan); err != nil {
+		log.Infof("[%s] %s", m.label(), err)
+		return nil, false
+	}
+	if pan.PairId == "" || !strings.HasSuffix(strings.ToUpper(m.Id), strings.ToUpper(pan.PairId)) {
+		log.Infof("[%s] Saved PAN does not match the configured meter.", m.label())
+		return nil, false
+	}
+
+	if err := mod.Load(); err != nil {
+		log.Infof("[%s] %s", m.label(), err)
+		return nil, false
+	}
+	regs, err := mod.Registers()
+	if err != nil {
+		log.Infof("[%s] %s", m.label(), err)
+		return nil, false
+	}
+	if regs.Channel != pan.Channel || regs.PanId != pan.PanId {
+		log.Infof("[%s] Saved module registers do not match the saved PAN.", m.label())
+		return nil, false
+	}
+
+	log.Infof("[%s] Restored PAN %04X on channel %02X, skipping scan.", m.label(), pan.PanId, pan.Channel)
+	return &pan, true
+}
+
+func savePan(mod bp.Module, m *meter, pan *panState) {
+	if m.State == "" {
+		return
+	}
+
+	if err := mod.Save(); err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+
+	f, err := os.Create(m.State)
+	if err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+	defer f.Close()
+
+	if err := toml.NewEncoder(f).Encode(pan); err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+	}
+}
+
+func runModule(ctrl bp.Controller, m *meter, args []string) error {
+	mod := bp.NewModule(ctrl)
+
+	op := "show"
+	if len(args) > 0 {
+		op = args[0]
+	}
+
+	switch op {
+	case "show":
+	case "load":
+		if err := mod.Load(); err != nil {
+			return err
+		}
+	case "save":
+		if err := mod.Save(); err != nil {
+			return err
+		}
+	case "erase", "reset":
+		if err := mod.Erase(); err != nil {
+			return err
+		}
+		if m.State != "" {
+			if err := os.Remove(m.State); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		fmt.Println("Saved module state erased.")
+		return nil
+	default:
+		return errors.New("Usage: smartmeter module [show|load|save|erase]")
+	}
+
+	regs, err := mod.Registers()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Channel:     %02X\n", regs.Channel)
+	fmt.Printf("Pan ID:      %04X\n", regs.PanId)
+	fmt.Printf("Auto reauth: %t\n", regs.AutoReauth)
+	fmt.Printf("Auto load:   %t\n", regs.AutoLoad)
+
+	if m.State != "" {
+		var pan panState
+		if _, err := toml.DecodeFile(m.State, &pan); err == nil {
+			fmt.Printf("Saved PAN:   %04X on channel %02X, addr %s (%s)\n", pan.PanId, pan.Channel, pan.Addr, pan.IpAddr)
+		}
+	}
+	return nil
+}
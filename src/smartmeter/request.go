@@ -0,0 +1,86 @@
+package main
+
+import (
+	bp "bp35a1"
+	"context"
+	"echonet"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// An ECHONET Lite node reached through the controller.
+type node struct {
+	ctrl   bp.Controller
+	addr   net.IP
+	hmutex *sync.Mutex // held across setting a history day and reading it
+}
+
+func newNode(ctrl bp.Controller, addr net.IP) *node {
+	return &node{ctrl: ctrl, addr: addr, hmutex: new(sync.Mutex)}
+}
+
+// Implements echonet.Requester, matching the response by TID.
+func (n *node) Request(ctx context.Context, deoj echonet.Eoj, esv echonet.Esv, props ...echonet.Property) (echonet.Frame, error) {
+	req := echonet.NewFrame()
+	req.SetSeoj(echonet.CLASS_CONTROLLER, 1)
+	req.SetDeoj(deoj.Class, deoj.Instance)
+	req.SetEsv(esv)
+	req.SetOpc(uint8(len(props)))
+	req.SetProperties(props)
+
+	tid := getTranId()
+	cmd, err := bp.SendTo(1, n.addr, bp.PORT_ECHONET, 1, req.Encode(tid))
+	if err != nil {
+		return nil, err
+	}
+
+	op := n.ctrl.Expect(
+		func(e bp.Event) bool {
+			if e.Type() != bp.ERXUDP || e.(bp.EventRxUDP).LPort() != bp.PORT_ECHONET {
+				return false
+			}
+			f, err := echonet.Decode(e.(bp.EventRxUDP).Data())
+			return err == nil && f.Tid() == tid
+		}, 20*time.Second)
+
+	r := n.ctrl.SendPriority(bp.INTERACTIVE, cmd)
+	if r == nil {
+		return nil, errors.New("No response.")
+	}
+	switch r.Type() {
+	case bp.FAIL:
+		return nil, fmt.Errorf("Command failed: %s", r.(bp.Fail).Code())
+	case bp.DROPPED:
+		return nil, errors.New("Command dropped.")
+	}
+
+	if err := op.Wait(ctx); err != nil {
+		return nil, err
+	}
+	e, _ := op.Result()
+	f, err := echonet.Decode(e.(bp.EventRxUDP).Data())
+	if err != nil {
+		return nil, err
+	}
+	switch f.Esv() {
+	case echonet.ESV_SETI_SNA, echonet.ESV_SETC_SNA, echonet.ESV_GET_SNA, echonet.ESV_INF_SNA, echonet.ESV_SET_GET_SNA:
+		return f, fmt.Errorf("Request %02X rejected by %04X:%d.", byte(esv), uint16(deoj.Class), deoj.Instance)
+	}
+	return f, nil
+}
+
+func (n *node) get(ctx context.Context, class echonet.Class, index uint8, epcs ...echonet.Epc) (echonet.Frame, error) {
+	return echonet.Get(ctx, n, echonet.Eoj{Class: class, Instance: index}, epcs...)
+}
+
+func property(f echonet.Frame, epc echonet.Epc) (echonet.Property, bool) {
+	for _, p := range f.Properties() {
+		if p.Epc() == epc {
+			return p, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	bp "bp35a1"
+	"context"
+	"echonet"
+	"fmt"
+	"github.com/influxdata/influxdb/client/v2"
+	"github.com/robfig/cron"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+func runSession(ctrl bp.Controller, m *meter, conf *config, cli client.Client) {
+	beacons := bp.NewBeaconView(ctrl)
+
+	mod := bp.NewModule(ctrl)
+	pan, ok := restorePan(mod, m)
+
+	pwd, err := bp.SetPassword(m.Pwd)
+	if err != nil {
+		log.Criticalf("[%s] %s", m.label(), err)
+		return
+	}
+	rbid, err := bp.SetRouteBId(m.Id)
+	if err != nil {
+		log.Criticalf("[%s] %s", m.label(), err)
+		return
+	}
+	ctrl.Send(pwd)
+	ctrl.Send(rbid)
+
+	var pans []*panState
+	if ok {
+		pans = []*panState{pan}
+	} else {
+		pans, err = scanPan(ctrl, m)
+		if err != nil {
+			log.Criticalf("[%s] %s", m.label(), err)
+			return
+		}
+	}
+
+	pan, inf, err := newJoiner(ctrl, mod, m, &conf.Join, cli).join(pans)
+	if err != nil {
+		log.Criticalf("[%s] %s", m.label(), err)
+		return
+	}
+	if !ok || pan != pans[0] {
+		savePan(mod, m, pan)
+	}
+	addr := net.ParseIP(pan.IpAddr)
+
+	n := newNode(ctrl, addr)
+
+	var np echonet.NodeProfile
+	if err := inf.Wait(context.Background()); err == nil {
+		e, _ := inf.Result()
+		if f, err := echonet.Decode(e.(bp.EventRxUDP).Data()); err == nil {
+			np.Update(f)
+		}
+	}
+	if np.InstanceList == nil {
+		log.Infof("[%s] No instance list notification, asking for it.", m.label())
+		l, err := echonet.InstanceList(context.Background(), n)
+		if err != nil {
+			log.Errorf("[%s] %s", m.label(), err)
+		}
+		np.InstanceList = l
+	}
+
+	var index uint8
+	if eoj, ok := np.InstanceList.Find(echonet.CLASS_SMART_EE_METER); ok {
+		index = eoj.Instance
+	}
+
+	if index <= 0 {
+		log.Criticalf("[%s] No indexes found.", m.label())
+		return
+	}
+
+	req := echonet.NewFrame()
+	req.SetSeoj(echonet.CLASS_CONTROLLER, 1)
+	req.SetDeoj(echonet.CLASS_SMART_EE_METER, index)
+	req.SetEsv(echonet.ESV_GET)
+	req.SetOpc(1)
+	p := echonet.NewProperty()
+	p.SetEpc(echonet.EPC_0288_UNIT_FOR_CM_AMTS_OF_EE)
+	p.SetPdc(0)
+	req.SetProperties([]echonet.Property{p})
+
+	tid := getTranId()
+	get, err := bp.SendTo(1, addr, bp.PORT_ECHONET, 1, req.Encode(tid))
+	if err != nil {
+		log.Criticalf("[%s] %s", m.label(), err)
+		return
+	}
+
+	var unit float32
+	ctrl.Send(get,
+		func(e bp.Event) bool {
+			if e.Type() == bp.ERXUDP {
+				f, err := echonet.Decode(e.(bp.EventRxUDP).Data())
+				if err != nil {
+					log.Warnf("[%s] %s", m.label(), err)
+					return false
+				}
+				seoj, idx := f.Seoj()
+				if f.Tid() == tid && seoj == echonet.CLASS_SMART_EE_METER && idx == index && f.Esv() == echonet.ESV_GET_RES && f.Opc() > 0 {
+					v, err := echonet.DecodeValue(seoj, f.Properties()[0])
+					if err != nil {
+						log.Warnf("[%s] %s", m.label(), err)
+						return false
+					}
+					u, ok := v.(echonet.Unit)
+					if !ok {
+						return false
+					}
+					unit = float32(u.Kwh())
+					return true
+				}
+			}
+			return false
+		})
+
+	tids := newInflight()
+	err = ctrl.RegisterService(bp.PORT_ECHONET,
+		func(e bp.EventRxUDP) {
+			f, err := echonet.Decode(e.Data())
+			if err != nil {
+				log.Warnf("[%s] %s", m.label(), err)
+				return
+			}
+			seoj, idx := f.Seoj()
+			if seoj == echonet.CLASS_SMART_EE_METER && idx == index && f.Esv() == echonet.ESV_GET_RES {
+				if !tids.done(f.Tid()) {
+					log.Debugf("[%s] Ignore response with unknown TID %04X", m.label(), f.Tid())
+					return
+				}
+				for _, p := range f.Properties() {
+					switch p.Epc() {
+					case echonet.EPC_0288_CM_AMTS_OF_EE_AT_FT_NDIR:
+						v, err := echonet.DecodeValue(seoj, p)
+						if err != nil {
+							log.Warnf("[%s] %s", m.label(), err)
+							continue
+						}
+						a := v.(echonet.Amount)
+						writePoint(cli, m, "WattHour", map[string]interface{}{"watthour": unit * float32(a.Value)}, a.Time)
+					case echonet.EPC_0288_INST_EE:
+						v, err := echonet.DecodeValue(seoj, p)
+						if err != nil {
+							log.Warnf("[%s] %s", m.label(), err)
+							continue
+						}
+						writePoint(cli, m, "Watt", map[string]interface{}{"watt": v.(int32)}, time.Now())
+					}
+				}
+			}
+		})
+	if err != nil {
+		log.Criticalf("[%s] %s", m.label(), err)
+		return
+	}
+
+	caps, err := echonet.Capabilities(context.Background(), n, echonet.Eoj{Class: echonet.CLASS_SMART_EE_METER, Instance: index})
+	if err != nil {
+		log.Warnf("[%s] %s", m.label(), err)
+		if caps != nil {
+			log.Infof("[%s] Using the access rules of the class.", m.label())
+		}
+	} else {
+		names := make([]string, len(caps.Get))
+		for i, epc := range caps.Get {
+			names[i] = echonet.PropertyName(echonet.CLASS_SMART_EE_METER, epc)
+		}
+		log.Debugf("[%s] Get properties: %s", m.label(), strings.Join(names, ", "))
+	}
+	supports := func(epc echonet.Epc) bool {
+		return caps == nil || caps.CanGet(epc)
+	}
+
+	inventory(n, index, m, 5*time.Minute)
+
+	if conf.History.Days > 0 && supports(echonet.EPC_0288_HDATA_OF_CM_AMTS_OF_EE_NDIR) {
+		backfill(n, index, m, conf.History.Days, cli)
+	}
+
+	var sl *sleeper
+	if conf.Sleep.Enabled {
+		sl = newSleeper(ctrl, time.Duration(conf.Sleep.Idle)*time.Second)
+	}
+
+	mon := bp.NewLinkMonitor(ctrl, pan.Addr, pan.LQI, addr, &bp.MonitorConfig{
+		Interval: time.Duration(conf.Link.Interval) * time.Second,
+		Window:   conf.Link.Window,
+		MaxLoss:  conf.Link.MaxLoss,
+		MaxRTT:   time.Duration(conf.Link.MaxRTT) * time.Millisecond})
+	mon.RegisterHandler(bp.SAMPLE,
+		func(s bp.LinkState) {
+			writePoint(cli, m, "Link", map[string]interface{}{
+				"rtt":      s.RTT().Seconds() * 1000,
+				"avg_rtt":  s.AvgRTT().Seconds() * 1000,
+				"loss":     s.Loss(),
+				"lqi":      int(s.LQI()),
+				"degraded": s.Degraded()}, s.Time())
+			// The ping woke the module outside of a job.
+			sl.rearm()
+		})
+	var degraded time.Time
+	mon.RegisterHandler(bp.DEGRADED,
+		func(s bp.LinkState) {
+			log.Warnf("[%s] Link degraded: loss=%.2f rtt=%v lqi=%d", m.label(), s.Loss(), s.AvgRTT(), s.LQI())
+			degraded = s.Time()
+			go probePan(ctrl, beacons, m, pan)
+		})
+	mon.RegisterHandler(bp.RECOVERED,
+		func(s bp.LinkState) {
+			log.Infof("[%s] Link recovered: loss=%.2f rtt=%v lqi=%d", m.label(), s.Loss(), s.AvgRTT(), s.LQI())
+			if !degraded.IsZero() && supports(echonet.EPC_0288_HDATA_OF_CM_AMTS_OF_EE_RDIR_2) {
+				go fillGap(n, index, m, degraded, cli)
+			}
+		})
+	mon.Start()
+
+	cr := cron.New()
+	if supports(echonet.EPC_0288_CM_AMTS_OF_EE_AT_FT_NDIR) {
+		cr.AddFunc("5 */10 * * * *", sl.job(func() {
+			poll(ctrl, m, addr, index, tids, echonet.EPC_0288_CM_AMTS_OF_EE_AT_FT_NDIR)
+		}))
+	}
+
+	if supports(echonet.EPC_0288_INST_EE) {
+		cr.AddFunc("*/10 * * * * *", sl.job(func() {
+			poll(ctrl, m, addr, index, tids, echonet.EPC_0288_INST_EE)
+		}))
+	}
+
+	cr.AddFunc("0 * * * * *", func() {
+		writeStats(cli, m, ctrl.Stats())
+	})
+
+	cr.Start()
+	sl.job(func() {})()
+}
+
+// Asks the meter for the property; the response is handled by the ECHONET
+// service. Waits for it, so that a sleeper job keeps the module awake until
+// the response has arrived or timed out.
+func poll(ctrl bp.Controller, m *meter, addr net.IP, index uint8, tids *inflight, epc echonet.Epc) {
+	req := echonet.NewFrame()
+	req.SetSeoj(echonet.CLASS_CONTROLLER, 1)
+	req.SetDeoj(echonet.CLASS_SMART_EE_METER, index)
+	req.SetEsv(echonet.ESV_GET)
+	req.SetOpc(1)
+	p := echonet.NewProperty()
+	p.SetEpc(epc)
+	p.SetPdc(0)
+	req.SetProperties([]echonet.Property{p})
+
+	tid := getTranId()
+	c, err := bp.SendTo(1, addr, bp.PORT_ECHONET, 1, req.Encode(tid))
+	if err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+	tids.add(tid)
+
+	op := ctrl.Expect(
+		func(e bp.Event) bool {
+			if e.Type() != bp.ERXUDP || e.(bp.EventRxUDP).LPort() != bp.PORT_ECHONET {
+				return false
+			}
+			f, err := echonet.Decode(e.(bp.EventRxUDP).Data())
+			return err == nil && f.Tid() == tid
+		}, 20*time.Second)
+	if r := ctrl.SendPriority(bp.SCHEDULED, c); r == nil || r.Type() != bp.OK {
+		op.Cancel()
+		return
+	}
+	if err := op.Wait(context.Background()); err != nil {
+		log.Debugf("[%s] No response for %02X: %s", m.label(), byte(epc), err)
+	}
+}
+
+func probePan(ctrl bp.Controller, beacons bp.BeaconView, m *meter, pan *panState) {
+	if pan.Channel < 33 || pan.Channel > 60 {
+		return
+	}
+	scan, err := bp.Scan(2, 1<<(pan.Channel-33), 4)
+	if err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+
+	start := time.Now()
+	if err := ctrl.Begin(scan).Wait(context.Background()); err != nil {
+		log.Errorf("[%s] %s", m.label(), err)
+		return
+	}
+
+	if c := beacons.Coordinator(pan.PanId); c == nil || c.LastSeen().Before(start) {
+		log.Warnf("[%s] PAN %04X is not beaconing; the meter may be down.", m.label(), pan.PanId)
+	} else {
+		log.Warnf("[%s] PAN %04X is still beaconing (LQI %d); the link is degraded.", m.label(), pan.PanId, c.LQI())
+	}
+}
+
+func writeStats(cli client.Client, m *meter, st bp.Stats) {
+	fields := map[string]interface{}{
+		"timeouts":   int64(st.Timeouts()),
+		"malformed":  int64(st.Malformed()),
+		"unmatched":  int64(st.Unmatched()),
+		"dropped":    int64(st.Dropped()),
+		"stalls":     int64(st.Stalls()),
+		"bytes_in":   int64(st.BytesIn()),
+		"bytes_out":  int64(st.BytesOut()),
+		"latency_n":  int64(st.Latency().Count()),
+		"latency_ms": st.Latency().Sum().Seconds() * 1000}
+	for k, v := range st.Commands() {
+		fields["cmd_"+k] = int64(v)
+	}
+	for k, v := range st.Responses() {
+		fields["resp_"+k.String()] = int64(v)
+	}
+	for k, v := range st.Events() {
+		fields["ev_"+k.String()] = int64(v)
+	}
+	for k, v := range st.UnhandledUDP() {
+		fields[fmt.Sprintf("udp_unhandled_%d", k)] = int64(v)
+	}
+	for k, v := range st.QueueDepth() {
+		fields["queue_"+k.String()] = v
+	}
+
+	writePoint(cli, m, "Controller", fields, time.Now())
+}
+
+// A point of the batch written by writePoints.
+type sample struct {
+	fields map[string]interface{}
+	time   time.Time
+}
+
+func writePoint(cli client.Client, m *meter, name string, fields map[string]interface{}, t time.Time) {
+	writePoints(cli, m, name, []sample{{fields: fields, time: t}})
+}
+
+// Writes the samples as one batch.
+func writePoints(cli client.Client, m *meter, name string, samples []sample) {
+	bps, _ := client.NewBatchPoints(client.BatchPointsConfig{
+		Database:  "wattmeter",
+		Precision: "s",
+	})
+
+	for _, s := range samples {
+		pt, err := client.NewPoint(name, m.tags(), s.fields, s.time)
+		if err != nil {
+			log.Errorf("[%s] %s", m.label(), err)
+			continue
+		}
+		bps.AddPoint(pt)
+	}
+	cli.Write(bps)
+}
+
+// TIDs of the requests that are still waiting for a response.
+type inflight struct {
+	mutex *sync.Mutex
+	tids  map[uint16]time.Time
+}
+
+func newInflight() *inflight {
+	return &inflight{
+		mutex: new(sync.Mutex),
+		tids:  make(map[uint16]time.Time)}
+}
+
+func (t *inflight) add(tid uint16) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tids[tid] = time.Now()
+}
+
+func (t *inflight) done(tid uint16) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for k, v := range t.tids {
+		if time.Since(v) > 10*time.Minute {
+			delete(t.tids, k)
+		}
+	}
+	_, ok := t.tids[tid]
+	delete(t.tids, tid)
+	return ok
+}
+
+func (m *meter) tags() map[string]string {
+	return map[string]string{"meter": m.label()}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	bp "bp35a1"
+	"sync"
+	"time"
+
+	log "github.com/cihub/seelog"
+)
+
+type sleeper struct {
+	ctrl  bp.Controller
+	idle  time.Duration
+	mutex *sync.Mutex
+	busy  int
+	timer *time.Timer
+}
+
+func newSleeper(ctrl bp.Controller, idle time.Duration) *sleeper {
+	if idle <= 0 {
+		idle = time.Second * 5
+	}
+	return &sleeper{
+		ctrl:  ctrl,
+		idle:  idle,
+		mutex: new(sync.Mutex)}
+}
+
+func (s *sleeper) job(f func()) func() {
+	if s == nil {
+		return f
+	}
+
+	return func() {
+		s.begin()
+		defer s.end()
+		f()
+	}
+}
+
+func (s *sleeper) begin() {
+	s.mutex.Lock()
+	s.busy++
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mutex.Unlock()
+
+	if err := s.ctrl.Wake(); err != nil {
+		log.Error(err)
+	}
+}
+
+func (s *sleeper) end() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.busy--
+	if s.busy == 0 {
+		s.timer = time.AfterFunc(s.idle, s.sleep)
+	}
+}
+
+// Puts the module back to sleep after the idle time when no job is running,
+// for wake-ups outside of jobs such as link monitor pings.
+func (s *sleeper) rearm() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.busy == 0 {
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		s.timer = time.AfterFunc(s.idle, s.sleep)
+	}
+}
+
+func (s *sleeper) sleep() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.busy == 0 {
+		if err := s.ctrl.Sleep(); err != nil {
+			log.Error(err)
+		}
+	}
+}